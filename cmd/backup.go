@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+// backupPassphraseEnv holds the passphrase of the backup archives, so it is not exposed in the process args
+const backupPassphraseEnv = "VAULT_CRED_BACKUP_PASSPHRASE"
+
+func runBackup(args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := flags.String("out", "", "backup archive file to write, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" {
+		return errors.New("backup archive file is required")
+	}
+
+	if *out == "-" {
+		return backup(os.Stdout)
+	}
+
+	if _, err := os.Stat(*out); err == nil {
+		return errors.Errorf("backup archive %s already exists", *out)
+	}
+	// the archive is written to a temporary file renamed on success, so a failed
+	// backup leaves no partial archive behind
+	f, err := os.CreateTemp(filepath.Dir(*out), "."+filepath.Base(*out)+".tmp-*")
+	if err != nil {
+		return errors.WithMessage(err, "failed to create backup archive")
+	}
+	defer os.Remove(f.Name())
+	if err := backup(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return errors.WithMessage(err, "failed to write backup archive")
+	}
+	if err := os.Rename(f.Name(), *out); err != nil {
+		return errors.WithMessage(err, "failed to write backup archive")
+	}
+	return nil
+}
+
+func backup(w io.Writer) error {
+	count, err := job.BackupCredentials(context.Background(), logging.NewLogger(), w, os.Getenv(backupPassphraseEnv))
+	if err != nil {
+		return errors.WithMessage(err, "failed to backup credentials")
+	}
+	fmt.Fprintf(os.Stderr, "backed up %d credentials\n", count)
+	return nil
+}
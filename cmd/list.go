@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+func runList(args []string) error {
+	flags := flag.NewFlagSet("list", flag.ContinueOnError)
+	credentialType := flags.String("type", "", "credential type filter, service|cert|generic")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch *credentialType {
+	case "", job.CredentialTypeService, job.CredentialTypeCert, job.CredentialTypeGeneric:
+	default:
+		return errors.Errorf("invalid credential type %s, supported types: service, cert, generic", *credentialType)
+	}
+
+	credentials, err := job.ListCredentials(context.Background(), logging.NewLogger(), *credentialType)
+	if err != nil {
+		return errors.WithMessage(err, "failed to list credentials")
+	}
+
+	for _, credential := range credentials {
+		metadata := make([]string, 0, len(credential.Metadata))
+		for key, val := range credential.Metadata {
+			metadata = append(metadata, key+"="+val)
+		}
+		sort.Strings(metadata)
+		fmt.Printf("%s\t%s\t%s\n", credential.Path, credential.Type, strings.Join(metadata, ","))
+	}
+	return nil
+}
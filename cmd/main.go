@@ -1,7 +1,37 @@
 package main
 
-import "github.com/intelops/vault-cred/server"
+import (
+	"fmt"
+	"os"
+
+	"github.com/intelops/vault-cred/server"
+)
 
 func main() {
-	server.Start()
+	if len(os.Args) < 2 {
+		server.Start()
+		return
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "push":
+		err = runPush(os.Args[2:])
+	case "list":
+		err = runList(os.Args[2:])
+	case "rewrap":
+		err = runRewrap(os.Args[2:])
+	case "backup":
+		err = runBackup(os.Args[2:])
+	case "restore":
+		err = runRestore(os.Args[2:])
+	case "move":
+		err = runMove(os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %s, supported commands: push, list, rewrap, backup, restore, move", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 }
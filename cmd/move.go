@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+func runMove(args []string) error {
+	flags := flag.NewFlagSet("move", flag.ContinueOnError)
+	mountPath := flags.String("mount", "", "vault mount of the credential, defaults to the credential mount")
+	from := flags.String("from", "", "current vault path of the credential")
+	to := flags.String("to", "", "new vault path of the credential")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return errors.New("both --from and --to paths are required")
+	}
+
+	if err := job.MoveCredential(context.Background(), logging.NewLogger(), *mountPath, *from, *to); err != nil {
+		return errors.WithMessage(err, "failed to move credential")
+	}
+	fmt.Printf("moved %s to %s\n", *from, *to)
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+const pushUsage = `usage: vault-cred push <service|cert|generic> [flags]
+
+  service --entity <name> --id <identifier> --user <user> --password <password>
+  cert    --entity <name> --id <identifier> --ca-file <path> --cert-file <path> --key-file <path>
+  generic --type <type> --entity <name> --id <identifier> --data key=value [--data key=value ...]`
+
+// keyValueFlags collects the repeated key=value flag values
+type keyValueFlags map[string]string
+
+func (f keyValueFlags) String() string {
+	return fmt.Sprintf("%v", map[string]string(f))
+}
+
+func (f keyValueFlags) Set(value string) error {
+	key, val, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return errors.Errorf("invalid key=value %q", value)
+	}
+	f[key] = val
+	return nil
+}
+
+// credentialData returns the flat key=value flags as generic credential data
+func (f keyValueFlags) credentialData() map[string]interface{} {
+	data := make(map[string]interface{}, len(f))
+	for key, val := range f {
+		data[key] = val
+	}
+	return data
+}
+
+func runPush(args []string) error {
+	if len(args) == 0 {
+		return errors.New(pushUsage)
+	}
+
+	var secretKey string
+	var secretData interface{}
+	flags := flag.NewFlagSet("push "+args[0], flag.ContinueOnError)
+	entity := flags.String("entity", "", "credential entity name")
+	id := flags.String("id", "", "credential identifier")
+	switch args[0] {
+	case "service":
+		user := flags.String("user", "", "service user name")
+		password := flags.String("password", "", "service password")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		secretKey = job.ServiceCredentialKeyPrefix + "-cli"
+		secretData = job.ServiceCredentail{EntityName: *entity, CredIndentifier: *id, UserName: *user, Password: *password}
+	case "cert":
+		caFile := flags.String("ca-file", "", "ca certificate pem file")
+		certFile := flags.String("cert-file", "", "certificate pem file")
+		keyFile := flags.String("key-file", "", "private key pem file")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		certData := job.CertificateData{EntityName: *entity, CertIndentifier: *id}
+		for _, file := range []struct {
+			path  string
+			field *string
+		}{{*caFile, &certData.CACert}, {*certFile, &certData.Cert}, {*keyFile, &certData.Key}} {
+			data, err := os.ReadFile(file.path)
+			if err != nil {
+				return errors.WithMessage(err, "failed to read certificate file")
+			}
+			*file.field = string(data)
+		}
+		secretKey = job.CertificateKeyPrefix + "-cli"
+		secretData = certData
+	case "generic":
+		credentialType := flags.String("type", "", "credential type")
+		data := keyValueFlags{}
+		flags.Var(data, "data", "credential key=value, repeatable")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		secretKey = job.GenericCredentialKeyPrefix + "-cli"
+		secretData = job.GenericCredential{CredentialType: *credentialType, EntityName: *entity,
+			CredIndentifier: *id, Credential: data.credentialData()}
+	default:
+		return errors.New(pushUsage)
+	}
+
+	data, err := json.Marshal(secretData)
+	if err != nil {
+		return err
+	}
+	pushed, err := job.PushCredential(context.Background(), logging.NewLogger(), secretKey, string(data))
+	if err != nil {
+		return errors.WithMessage(err, "failed to push credential")
+	}
+	for _, p := range pushed {
+		if p.Written {
+			fmt.Printf("written %s\n", p.Path)
+		} else {
+			fmt.Printf("skipped %s, %s\n", p.Path, p.SkipReason)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+func runRestore(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	from := flags.String("from", "", "backup archive file to restore")
+	overwrite := flags.Bool("overwrite", false, "overwrite the credentials already in vault, else they are skipped")
+	dryRun := flags.Bool("dry-run", false, "only print the credentials that would be restored")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("backup archive file is required")
+	}
+
+	f, err := os.Open(*from)
+	if err != nil {
+		return errors.WithMessage(err, "failed to open backup archive")
+	}
+	defer f.Close()
+
+	result, err := job.RestoreCredentials(context.Background(), logging.NewLogger(), f,
+		os.Getenv(backupPassphraseEnv), *overwrite, *dryRun)
+	fmt.Printf("restored %d, skipped %d, failed %d credentials\n", result.Restored, result.Skipped, result.Failed)
+	if err != nil {
+		return errors.WithMessage(err, "failed to restore credentials")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+	"github.com/pkg/errors"
+)
+
+func runRewrap(args []string) error {
+	flags := flag.NewFlagSet("rewrap", flag.ContinueOnError)
+	credentialType := flags.String("type", "", "credential type filter, service|cert|generic")
+	transitKey := flags.String("key", "", "transit key name, defaults to the kv encryption key")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	switch *credentialType {
+	case "", job.CredentialTypeService, job.CredentialTypeCert, job.CredentialTypeGeneric:
+	default:
+		return errors.Errorf("invalid credential type %s, supported types: service, cert, generic", *credentialType)
+	}
+
+	paths, err := job.RewrapCredentials(context.Background(), logging.NewLogger(), *credentialType, *transitKey)
+	for _, path := range paths {
+		fmt.Printf("rewrapped %s\n", path)
+	}
+	if err != nil {
+		return errors.WithMessage(err, "failed to rewrap credentials")
+	}
+	return nil
+}
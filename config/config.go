@@ -7,27 +7,124 @@ import (
 )
 
 type Configuration struct {
-	Host                     string `envconfig:"HOST" default:"0.0.0.0"`
-	Port                     int    `envconfig:"PORT" default:"9098"`
-	VaultSealWatchInterval   string `envconfig:"VAULT_SEAL_WATCH_INTERVAL"`
-	VaultPolicyWatchInterval string `envconfig:"VAULT_POLICY_WATCH_INTERVAL"`
-	VaultCredSyncInterval    string `envconfig:"VAULT_CRED_SYNC_INTERVAL"`
+	Host                     string        `envconfig:"HOST" default:"0.0.0.0"`
+	Port                     int           `envconfig:"PORT" default:"9098"`
+	HTTPPort                 int           `envconfig:"HTTP_PORT" default:"9099"`
+	VaultSealWatchInterval   string        `envconfig:"VAULT_SEAL_WATCH_INTERVAL"`
+	VaultPolicyWatchInterval string        `envconfig:"VAULT_POLICY_WATCH_INTERVAL"`
+	VaultCredSyncInterval    string        `envconfig:"VAULT_CRED_SYNC_INTERVAL"`
+	SyncAPIToken             string        `envconfig:"SYNC_API_TOKEN"`
+	ShutdownGracePeriod      time.Duration `envconfig:"SHUTDOWN_GRACE_PERIOD" default:"30s"`
+	StatusRequiresToken      bool          `envconfig:"STATUS_REQUIRES_TOKEN" default:"false"`
 }
 
 type VaultEnv struct {
-	HAEnabled                  bool          `envconfig:"HA_ENABLED" default:"true"`
-	Address                    string        `envconfig:"VAULT_ADDR" required:"true"`
-	NodeAddresses              []string      `envconfig:"VAULT_NODE_ADDRESSES" required:"true"`
-	CACert                     string        `envconfig:"VAULT_CACERT" required:"false"`
-	ReadTimeout                time.Duration `envconfig:"VAULT_READ_TIMEOUT" default:"60s"`
-	MaxRetries                 int           `envconfig:"VAULT_MAX_RETRIES" default:"5"`
-	VaultTokenForRequests      bool          `envconfig:"VAULT_TOKEN_FOR_REQUESTS" default:"false"`
-	VaultSecretName            string        `envconfig:"VAULT_SECRET_NAME" default:"vault-server"`
-	VaultSecretNameSpace       string        `envconfig:"POD_NAMESPACE" required:"true"`
-	VaultSecretTokenKeyName    string        `envconfig:"VAULT_SECRET_TOKEN_KEY_NAME" default:"root-token"`
-	VaultSecretUnSealKeyPrefix string        `envconfig:"VAULT_SECRET_UNSEAL_KEY_PREFIX" default:"unsealkey"`
-	VaultToken                 string        `envconfig:"VAULT_TOKEN"`
-	VaultCredSyncSecretName    string        `envconfig:"VAULT_CRED_SYNC_SECRET_NAME" default:"vault-cred-sync-data"`
+	HAEnabled                  bool              `envconfig:"HA_ENABLED" default:"true"`
+	Address                    string            `envconfig:"VAULT_ADDR"`
+	NodeAddresses              []string          `envconfig:"VAULT_NODE_ADDRESSES" required:"true"`
+	CACert                     string            `envconfig:"VAULT_CACERT" required:"false"`
+	VaultClientCertFile        string            `envconfig:"VAULT_CLIENT_CERT_FILE"`
+	VaultClientKeyFile         string            `envconfig:"VAULT_CLIENT_KEY_FILE"`
+	ReadTimeout                time.Duration     `envconfig:"VAULT_READ_TIMEOUT" default:"60s"`
+	MaxRetries                 int               `envconfig:"VAULT_MAX_RETRIES" default:"5"`
+	VaultTokenForRequests      bool              `envconfig:"VAULT_TOKEN_FOR_REQUESTS" default:"false"`
+	VaultSecretName            string            `envconfig:"VAULT_SECRET_NAME" default:"vault-server"`
+	VaultSecretNameSpace       string            `envconfig:"POD_NAMESPACE" required:"true"`
+	VaultSecretTokenKeyName    string            `envconfig:"VAULT_SECRET_TOKEN_KEY_NAME" default:"root-token"`
+	VaultSecretUnSealKeyPrefix string            `envconfig:"VAULT_SECRET_UNSEAL_KEY_PREFIX" default:"unsealkey"`
+	VaultToken                 string            `envconfig:"VAULT_TOKEN"`
+	VaultAuthMethod            string            `envconfig:"VAULT_AUTH_METHOD" default:"token"`
+	AppRoleMountPath           string            `envconfig:"VAULT_APPROLE_MOUNT_PATH" default:"approle"`
+	AppRoleID                  string            `envconfig:"VAULT_APPROLE_ROLE_ID"`
+	AppRoleIDFile              string            `envconfig:"VAULT_APPROLE_ROLE_ID_FILE"`
+	AppRoleSecretID            string            `envconfig:"VAULT_APPROLE_SECRET_ID"`
+	AppRoleSecretIDFile        string            `envconfig:"VAULT_APPROLE_SECRET_ID_FILE"`
+	K8sAuthRole                string            `envconfig:"VAULT_K8S_AUTH_ROLE"`
+	K8sAuthMountPath           string            `envconfig:"VAULT_K8S_AUTH_MOUNT_PATH" default:"kubernetes"`
+	K8sAuthTokenPath           string            `envconfig:"VAULT_K8S_AUTH_TOKEN_PATH" default:"/var/run/secrets/kubernetes.io/serviceaccount/token"`
+	VaultNamespace             string            `envconfig:"VAULT_NAMESPACE"`
+	KVVersion                  int               `envconfig:"VAULT_KV_VERSION" default:"0"`
+	VaultCredSyncSecretName    string            `envconfig:"VAULT_CRED_SYNC_SECRET_NAME" default:"vault-cred-sync-data"`
+	SyncSourceKind             string            `envconfig:"VAULT_CRED_SYNC_SOURCE_KIND" default:"secret"`
+	SyncSourceDir              string            `envconfig:"VAULT_CRED_SYNC_SOURCE_DIR"`
+	SyncSecretSelector         string            `envconfig:"VAULT_CRED_SYNC_SECRET_SELECTOR"`
+	SyncSecretPrefix           string            `envconfig:"VAULT_CRED_SYNC_SECRET_PREFIX"`
+	SyncNamespaces             []string          `envconfig:"VAULT_CRED_SYNC_NAMESPACES"`
+	SyncNamespaceSelector      string            `envconfig:"VAULT_CRED_SYNC_NAMESPACE_SELECTOR"`
+	EnabledCredentialTypes     []string          `envconfig:"VAULT_CRED_ENABLED_TYPES"`
+	CredentialMountPaths       map[string]string `envconfig:"VAULT_CRED_MOUNT_PATHS"`
+	SecretPathPrefix           string            `envconfig:"VAULT_CRED_SECRET_PATH_PREFIX"`
+	CredentialPathTemplate     string            `envconfig:"VAULT_CRED_PATH_TEMPLATE"`
+	GenericRequiredKeys        map[string]string `envconfig:"VAULT_CRED_GENERIC_REQUIRED_KEYS"`
+	StrictGenericValidation    bool              `envconfig:"VAULT_CRED_STRICT_GENERIC_VALIDATION" default:"false"`
+	SyncConcurrency            int               `envconfig:"VAULT_CRED_SYNC_CONCURRENCY" default:"5"`
+	PruneOrphans               bool              `envconfig:"VAULT_CRED_PRUNE_ORPHANS" default:"false"`
+	CertExpiryWarningThreshold time.Duration     `envconfig:"VAULT_CRED_CERT_EXPIRY_WARNING_THRESHOLD" default:"720h"`
+	DryRun                     bool              `envconfig:"VAULT_CRED_SYNC_DRY_RUN" default:"false"`
+	VerifyWrites               bool              `envconfig:"VAULT_CRED_VERIFY_WRITES" default:"false"`
+	VaultWriteMaxAttempts      int               `envconfig:"VAULT_WRITE_MAX_ATTEMPTS" default:"3"`
+	VaultWriteMaxElapsedTime   time.Duration     `envconfig:"VAULT_WRITE_MAX_ELAPSED_TIME" default:"30s"`
+	PasswordLength             int               `envconfig:"VAULT_CRED_PASSWORD_LENGTH" default:"32"`
+	PasswordCharset            string            `envconfig:"VAULT_CRED_PASSWORD_CHARSET" default:"abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"`
+	VaultCacheSize             int               `envconfig:"VAULT_CACHE_SIZE" default:"0"`
+	VaultCacheTTL              time.Duration     `envconfig:"VAULT_CACHE_TTL" default:"5m"`
+	VaultOpTimeout             time.Duration     `envconfig:"VAULT_OP_TIMEOUT" default:"30s"`
+	SyncRunTimeout             time.Duration     `envconfig:"VAULT_CRED_SYNC_RUN_TIMEOUT" default:"10m"`
+	TransitMountPath           string            `envconfig:"VAULT_TRANSIT_MOUNT_PATH" default:"transit"`
+	TransitKeyName             string            `envconfig:"VAULT_TRANSIT_KEY_NAME"`
+	KVEncryptionKeyName        string            `envconfig:"VAULT_CRED_KV_ENCRYPTION_KEY_NAME"`
+	KVEncryptedFields          []string          `envconfig:"VAULT_CRED_KV_ENCRYPTED_FIELDS"`
+	AutoGenerateIdentifier     bool              `envconfig:"VAULT_CRED_AUTO_GENERATE_IDENTIFIER" default:"false"`
+	IdentifierLength           int               `envconfig:"VAULT_CRED_IDENTIFIER_LENGTH" default:"16"`
+	IdentifierCharset          string            `envconfig:"VAULT_CRED_IDENTIFIER_CHARSET" default:"abcdefghijklmnopqrstuvwxyz0123456789"`
+	GenericNestedEncoding      string            `envconfig:"VAULT_CRED_GENERIC_NESTED_ENCODING" default:"flatten"`
+	VaultTLSSkipVerify         bool              `envconfig:"VAULT_TLS_SKIP_VERIFY" default:"false"`
+	SyncJitterWindow           time.Duration     `envconfig:"VAULT_CRED_SYNC_JITTER_WINDOW" default:"0"`
+	VaultAddressFile           string            `envconfig:"VAULT_ADDR_FILE"`
+	VaultTokenFile             string            `envconfig:"VAULT_TOKEN_FILE"`
+	ForceResync                bool              `envconfig:"VAULT_CRED_FORCE_RESYNC" default:"false"`
+	RespectProtection          bool              `envconfig:"VAULT_CRED_RESPECT_PROTECTION" default:"false"`
+	K8sEvents                  bool              `envconfig:"VAULT_CRED_K8S_EVENTS" default:"false"`
+	VaultFallbackAddresses     []string          `envconfig:"VAULT_FALLBACK_ADDRS"`
+	MirrorWrites               bool              `envconfig:"VAULT_MIRROR_WRITES" default:"false"`
+	MaxCredentialBytes         int               `envconfig:"VAULT_CRED_MAX_CREDENTIAL_BYTES" default:"1048576"`
+	SyncMode                   string            `envconfig:"VAULT_CRED_SYNC_MODE" default:"cron"`
+	CredentialMaxVersions      map[string]int    `envconfig:"VAULT_CRED_MAX_VERSIONS"`
+	PropagateLabels            []string          `envconfig:"VAULT_CRED_PROPAGATE_LABELS"`
+	DefaultEntityName          string            `envconfig:"VAULT_CRED_DEFAULT_ENTITY_NAME"`
+	CredentialOwner            string            `envconfig:"VAULT_CRED_OWNER" default:"vault-cred"`
+	CertBackend                string            `envconfig:"VAULT_CRED_CERT_BACKEND" default:"kv"`
+	PKIMountPath               string            `envconfig:"VAULT_PKI_MOUNT_PATH" default:"pki"`
+	K8sMaxAttempts             int               `envconfig:"VAULT_CRED_K8S_MAX_ATTEMPTS" default:"5"`
+	K8sMaxElapsedTime          time.Duration     `envconfig:"VAULT_CRED_K8S_MAX_ELAPSED_TIME" default:"30s"`
+	AutoCreateMount            bool              `envconfig:"VAULT_CRED_AUTO_CREATE_MOUNT" default:"false"`
+	ExpandVariables            bool              `envconfig:"VAULT_CRED_EXPAND_VARIABLES" default:"false"`
+	VariablesSecretName        string            `envconfig:"VAULT_CRED_VARIABLES_SECRET_NAME"`
+	VariablesEnvPrefix         string            `envconfig:"VAULT_CRED_VARIABLES_ENV_PREFIX" default:"VAULT_CRED_VAR_"`
+	MaxErrorsBeforeAbort       int               `envconfig:"VAULT_CRED_MAX_ERRORS_BEFORE_ABORT" default:"0"`
+	CredentialTTLs             map[string]string `envconfig:"VAULT_CRED_TTLS"`
+	KeyNormalization           string            `envconfig:"VAULT_CRED_KEY_NORMALIZATION" default:"none"`
+	WebhookURL                 string            `envconfig:"VAULT_CRED_WEBHOOK_URL"`
+	WebhookSecret              string            `envconfig:"VAULT_CRED_WEBHOOK_SECRET"`
+	WebhookOnFailureOnly       bool              `envconfig:"VAULT_CRED_WEBHOOK_ON_FAILURE_ONLY" default:"false"`
+	WebhookMaxAttempts         int               `envconfig:"VAULT_CRED_WEBHOOK_MAX_ATTEMPTS" default:"3"`
+	WebhookTimeout             time.Duration     `envconfig:"VAULT_CRED_WEBHOOK_TIMEOUT" default:"10s"`
+	KeyRoutes                  map[string]string `envconfig:"VAULT_CRED_KEY_ROUTES"`
+	IncludeKeys                []string          `envconfig:"VAULT_CRED_INCLUDE_KEYS"`
+	ExcludeKeys                []string          `envconfig:"VAULT_CRED_EXCLUDE_KEYS"`
+	FailOnDuplicatePath        bool              `envconfig:"VAULT_CRED_FAIL_ON_DUPLICATE_PATH" default:"false"`
+	VaultReadAddress           string            `envconfig:"VAULT_READ_ADDR"`
+	K8sNotFoundRetries         int               `envconfig:"VAULT_CRED_K8S_NOT_FOUND_RETRIES" default:"0"`
+	K8sNotFoundRetryDelay      time.Duration     `envconfig:"VAULT_CRED_K8S_NOT_FOUND_RETRY_DELAY" default:"1s"`
+	StrictAdditionalData       bool              `envconfig:"VAULT_CRED_STRICT_ADDITIONAL_DATA" default:"false"`
+	CertFullChain              bool              `envconfig:"VAULT_CRED_CERT_FULL_CHAIN" default:"false"`
+	ValueTransforms            map[string]string `envconfig:"VAULT_CRED_VALUE_TRANSFORMS"`
+	MergeByEntity              bool              `envconfig:"VAULT_CRED_MERGE_BY_ENTITY" default:"false"`
+	VaultWriteRateLimit        float64           `envconfig:"VAULT_WRITE_RATE_LIMIT" default:"0"`
+	VaultWriteBurst            int               `envconfig:"VAULT_WRITE_BURST" default:"1"`
+	ResolveReferences          bool              `envconfig:"VAULT_CRED_RESOLVE_REFERENCES" default:"false"`
+	ReferenceAllowedPaths      []string          `envconfig:"VAULT_CRED_REFERENCE_ALLOWED_PATHS"`
+	SyncFailureThreshold       time.Duration     `envconfig:"VAULT_CRED_SYNC_FAILURE_THRESHOLD" default:"1h"`
 }
 
 func FetchConfiguration() (Configuration, error) {
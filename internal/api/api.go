@@ -3,6 +3,10 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"unicode"
 
 	"github.com/intelops/go-common/logging"
 	"github.com/intelops/vault-cred/config"
@@ -11,6 +15,18 @@ import (
 	"github.com/pkg/errors"
 )
 
+// credentialSecretPathTemplate builds the credential secret paths when configured
+var credentialSecretPathTemplate *template.Template
+
+// credentialSecretPathPrefix is prepended to every credential secret path, without surrounding slashes
+var credentialSecretPathPrefix string
+
+type credentialSecretPathData struct {
+	Type       string
+	EntityName string
+	Identifier string
+}
+
 type VaultCredServ struct {
 	vaultcredpb.UnimplementedVaultCredServer
 	conf config.VaultEnv
@@ -23,6 +39,14 @@ func NewVaultCredServ(log logging.Logger) (*VaultCredServ, error) {
 		return nil, err
 	}
 
+	if err := client.ValidateTLSConfig(conf); err != nil {
+		return nil, err
+	}
+
+	if err := ConfigureCredentialSecretPath(conf.SecretPathPrefix, conf.CredentialPathTemplate); err != nil {
+		return nil, err
+	}
+
 	return &VaultCredServ{
 		conf: conf,
 		log:  log,
@@ -33,8 +57,97 @@ func CredentialMountPath() string {
 	return "secret"
 }
 
+// ConfigureCredentialSecretPath sets the prefix of all the credential secret paths and the text/template
+// used to build the credential secret paths with the .Type, .EntityName and .Identifier variables.
+// An empty template keeps the default <type>/<entityName>/<identifier> layout.
+func ConfigureCredentialSecretPath(pathPrefix, pathTemplate string) error {
+	credentialSecretPathPrefix = strings.Trim(pathPrefix, "/")
+	if pathTemplate == "" {
+		credentialSecretPathTemplate = nil
+		return nil
+	}
+
+	tmpl, err := template.New("credentialSecretPath").Option("missingkey=error").Parse(pathTemplate)
+	if err != nil {
+		return errors.WithMessage(err, "invalid credential secret path template")
+	}
+
+	var samplePath strings.Builder
+	err = tmpl.Execute(&samplePath, credentialSecretPathData{Type: "type", EntityName: "entity", Identifier: "identifier"})
+	if err != nil {
+		return errors.WithMessage(err, "invalid credential secret path template")
+	}
+	if strings.TrimSpace(samplePath.String()) == "" {
+		return errors.New("credential secret path template renders an empty path")
+	}
+	credentialSecretPathTemplate = tmpl
+	return nil
+}
+
+// maxPathPartLength bounds the length of each credential path part
+const maxPathPartLength = 256
+
+// ValidateCredentialPathParts rejects credential types, entity names and identifiers which are not safe
+// to build a vault path from, being empty or overlong, or with slashes, path traversal segments,
+// percent encoded characters or control characters
+func ValidateCredentialPathParts(credentialType, credEntityName, credIdentifier string) error {
+	for _, part := range []struct {
+		name  string
+		value string
+	}{{"credential type", credentialType}, {"entity name", credEntityName}, {"identifier", credIdentifier}} {
+		if err := validatePathPart(part.value); err != nil {
+			return errors.WithMessagef(err, "invalid %s %q", part.name, part.value)
+		}
+	}
+	return nil
+}
+
+func validatePathPart(part string) error {
+	if part == "" {
+		return errors.New("empty value not allowed")
+	}
+	if len(part) > maxPathPartLength {
+		return errors.Errorf("longer than %d bytes not allowed", maxPathPartLength)
+	}
+	for _, r := range part {
+		if unicode.IsControl(r) {
+			return errors.New("control characters not allowed")
+		}
+	}
+	if strings.Contains(part, "/") {
+		return errors.New("slash not allowed")
+	}
+	if part == "." || part == ".." {
+		return errors.New("path traversal not allowed")
+	}
+	// an encoded value like %2e%2e may be decoded to a traversal segment on its way to vault
+	if unescaped, err := url.PathUnescape(part); err != nil || unescaped != part {
+		return errors.New("percent encoded characters not allowed")
+	}
+	return nil
+}
+
 func PrepareCredentialSecretPath(credentialType, credEntityName, credIdentifier string) string {
-	return fmt.Sprintf("%s/%s/%s", credentialType, credEntityName, credIdentifier)
+	secretPath := credentialSecretPath(credentialType, credEntityName, credIdentifier)
+	if credentialSecretPathPrefix == "" {
+		return secretPath
+	}
+	return credentialSecretPathPrefix + "/" + strings.TrimLeft(secretPath, "/")
+}
+
+func credentialSecretPath(credentialType, credEntityName, credIdentifier string) string {
+	if credentialSecretPathTemplate == nil {
+		return fmt.Sprintf("%s/%s/%s", credentialType, credEntityName, credIdentifier)
+	}
+
+	var secretPath strings.Builder
+	err := credentialSecretPathTemplate.Execute(&secretPath, credentialSecretPathData{
+		Type: credentialType, EntityName: credEntityName, Identifier: credIdentifier})
+	if err != nil {
+		// the template is validated when configured, so fall back to the default layout
+		return fmt.Sprintf("%s/%s/%s", credentialType, credEntityName, credIdentifier)
+	}
+	return secretPath.String()
 }
 
 func (v *VaultCredServ) GetCred(ctx context.Context, request *vaultcredpb.GetCredRequest) (*vaultcredpb.GetCredResponse, error) {
@@ -43,6 +156,9 @@ func (v *VaultCredServ) GetCred(ctx context.Context, request *vaultcredpb.GetCre
 		return nil, errors.WithMessage(err, "failed to initiize vault client")
 	}
 
+	if err := ValidateCredentialPathParts(request.CredentialType, request.CredEntityName, request.CredIdentifier); err != nil {
+		return nil, err
+	}
 	secretPath := PrepareCredentialSecretPath(request.CredentialType, request.CredEntityName, request.CredIdentifier)
 	credentail, err := vc.GetCredential(ctx, CredentialMountPath(), secretPath)
 	if err != nil {
@@ -59,6 +175,9 @@ func (v *VaultCredServ) PutCred(ctx context.Context, request *vaultcredpb.PutCre
 		return nil, errors.WithMessage(err, "failed to initiize vault client")
 	}
 
+	if err := ValidateCredentialPathParts(request.CredentialType, request.CredEntityName, request.CredIdentifier); err != nil {
+		return nil, err
+	}
 	secretPath := PrepareCredentialSecretPath(request.CredentialType, request.CredEntityName, request.CredIdentifier)
 	err = vc.PutCredential(ctx, CredentialMountPath(), secretPath, request.Credential)
 	if err != nil {
@@ -69,15 +188,20 @@ func (v *VaultCredServ) PutCred(ctx context.Context, request *vaultcredpb.PutCre
 	return &vaultcredpb.PutCredResponse{}, nil
 }
 
+// DeleteCred soft deletes the latest KV v2 version of the credential, the older versions are kept
+// and it can be undeleted. A credential which does not exist is taken as deleted.
 func (v *VaultCredServ) DeleteCred(ctx context.Context, request *vaultcredpb.DeleteCredRequest) (*vaultcredpb.DeleteCredResponse, error) {
 	vc, err := client.NewVaultClientForServiceAccount(ctx, v.log, v.conf)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := ValidateCredentialPathParts(request.CredentialType, request.CredEntityName, request.CredIdentifier); err != nil {
+		return nil, err
+	}
 	secretPath := PrepareCredentialSecretPath(request.CredentialType, request.CredEntityName, request.CredIdentifier)
-	err = vc.DeleteCredential(ctx, CredentialMountPath(), secretPath)
-	if err != nil {
+	err = vc.SoftDeleteCredential(ctx, CredentialMountPath(), secretPath)
+	if err != nil && !errors.Is(err, client.ErrCredentialNotFound) {
 		return nil, errors.WithMessage(err, "failed to delete credential")
 	}
 
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateCredentialPathParts(t *testing.T) {
+	tests := []struct {
+		name       string
+		credType   string
+		entityName string
+		identifier string
+		wantErr    bool
+	}{
+		{name: "valid", credType: "generic", entityName: "payments", identifier: "db-password"},
+		{name: "valid dots in name", credType: "generic", entityName: "payments.v2", identifier: "db..password"},
+		{name: "valid max length", credType: "generic", entityName: "payments", identifier: strings.Repeat("a", maxPathPartLength)},
+		{name: "dot dot entity", credType: "generic", entityName: "..", identifier: "db", wantErr: true},
+		{name: "dot identifier", credType: "generic", entityName: "payments", identifier: ".", wantErr: true},
+		{name: "dot dot type", credType: "..", entityName: "payments", identifier: "db", wantErr: true},
+		{name: "traversal sequence", credType: "generic", entityName: "../../sys", identifier: "db", wantErr: true},
+		{name: "leading slash", credType: "generic", entityName: "/payments", identifier: "db", wantErr: true},
+		{name: "trailing slash", credType: "generic", entityName: "payments", identifier: "db/", wantErr: true},
+		{name: "embedded slash", credType: "generic", entityName: "payments/admin", identifier: "db", wantErr: true},
+		{name: "encoded traversal", credType: "generic", entityName: "%2e%2e", identifier: "db", wantErr: true},
+		{name: "encoded slash", credType: "generic", entityName: "payments%2fadmin", identifier: "db", wantErr: true},
+		{name: "invalid percent encoding", credType: "generic", entityName: "payments%zz", identifier: "db", wantErr: true},
+		{name: "nul", credType: "generic", entityName: "payments\x00", identifier: "db", wantErr: true},
+		{name: "newline", credType: "generic", entityName: "payments", identifier: "db\npassword", wantErr: true},
+		{name: "empty type", credType: "", entityName: "payments", identifier: "db", wantErr: true},
+		{name: "empty entity", credType: "generic", entityName: "", identifier: "db", wantErr: true},
+		{name: "empty identifier", credType: "generic", entityName: "payments", identifier: "", wantErr: true},
+		{name: "overlong", credType: "generic", entityName: "payments", identifier: strings.Repeat("a", maxPathPartLength+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCredentialPathParts(tt.credType, tt.entityName, tt.identifier)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCredentialPathParts(%q, %q, %q) error = %v, wantErr %v",
+					tt.credType, tt.entityName, tt.identifier, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -10,10 +11,32 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
+// eventSourceComponent is the source component of the kubernetes events
+const eventSourceComponent = "vault-cred"
+
+// ErrSecretNotFound is returned when the requested secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// ErrConfigMapNotFound is returned when the requested configmap does not exist.
+var ErrConfigMapNotFound = errors.New("configmap not found")
+
+// IsK8sRetryableError reports whether the kubernetes api error is transient, as when the api server is
+// unreachable, and not a missing object or a request rejected by the api server
+func IsK8sRetryableError(err error) bool {
+	if err == nil || errors.Is(err, ErrSecretNotFound) || errors.Is(err, ErrConfigMapNotFound) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return !(k8serrors.IsNotFound(err) || k8serrors.IsForbidden(err) || k8serrors.IsUnauthorized(err) ||
+		k8serrors.IsBadRequest(err) || k8serrors.IsInvalid(err) || k8serrors.IsMethodNotSupported(err))
+}
+
 type K8SClient struct {
 	client *kubernetes.Clientset
 	log    logging.Logger
@@ -24,11 +47,17 @@ type ConfigMapData struct {
 	Namespace       string
 	Data            map[string]string
 	LastUpdatedTime time.Time
+	Labels          map[string]string
+	Annotations     map[string]string
 }
 
 type SecretData struct {
+	Name            string
+	Namespace       string
 	Data            map[string]string
 	LastUpdatedTime time.Time
+	Labels          map[string]string
+	Annotations     map[string]string
 }
 
 func NewK8SClient(log logging.Logger) (*K8SClient, error) {
@@ -48,6 +77,42 @@ func (k *K8SClient) GetClusterConfig() (*rest.Config, error) {
 	return rest.InClusterConfig()
 }
 
+// CheckConnectivity verifies the kubernetes api server is reachable
+func (k *K8SClient) CheckConnectivity(ctx context.Context) error {
+	if _, err := k.client.Discovery().ServerVersion(); err != nil {
+		return errors.WithMessage(err, "failed to reach kubernetes api server")
+	}
+	return nil
+}
+
+// CreateEvent records a kubernetes event on the object, eventType is Normal or Warning
+func (k *K8SClient) CreateEvent(ctx context.Context, kind, namespace, name, eventType, reason, message string) error {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", name, now.UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       kind,
+			Namespace:  namespace,
+			Name:       name,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: eventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := k.client.CoreV1().Events(namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		return errors.WithMessagef(err, "error in creating event for %s %s/%s", kind, namespace, name)
+	}
+	return nil
+}
+
 func (k *K8SClient) CreateOrUpdateSecret(ctx context.Context, secretName, namespace string, data map[string]string) error {
 	secData := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
@@ -79,10 +144,57 @@ func (k *K8SClient) GetSecret(ctx context.Context, secretName, namespace string)
 	secData, err := k.client.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
 	if err != nil {
 		if k8serrors.IsNotFound(err) {
-			return nil, errors.New("secret not found")
+			return nil, ErrSecretNotFound
 		}
 		return nil, errors.WithMessage(err, "error in creating vault secret")
 	}
+
+	k.log.Debugf("Secret %s fetched from namespace %s", secretName, namespace)
+	return toSecretData(secData)
+}
+
+// ListSecrets returns the secrets of the namespace matching the label selector and name prefix,
+// an empty selector or prefix matches all the secrets
+func (k *K8SClient) ListSecrets(ctx context.Context, namespace, labelSelector, namePrefix string) ([]*SecretData, error) {
+	secList, err := k.client.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list secrets in namespace %s", namespace)
+	}
+
+	secrets := []*SecretData{}
+	for i := range secList.Items {
+		if !strings.HasPrefix(secList.Items[i].Name, namePrefix) {
+			continue
+		}
+		secret, err := toSecretData(&secList.Items[i])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid secret %s in namespace %s", secList.Items[i].Name, namespace)
+		}
+		secrets = append(secrets, secret)
+	}
+	k.log.Debugf("%d secrets listed from namespace %s", len(secrets), namespace)
+	return secrets, nil
+}
+
+// WatchSecrets watches the secrets of the namespace matching the label selector,
+// only the named secret when the name is set
+func (k *K8SClient) WatchSecrets(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error) {
+	w, err := k.client.CoreV1().Secrets(namespace).Watch(ctx, watchListOptions(name, labelSelector))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to watch secrets in namespace %s", namespace)
+	}
+	return w, nil
+}
+
+func watchListOptions(name, labelSelector string) metav1.ListOptions {
+	listOptions := metav1.ListOptions{LabelSelector: labelSelector}
+	if name != "" {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+	return listOptions
+}
+
+func toSecretData(secData *corev1.Secret) (*SecretData, error) {
 	lastUpdatedTime, err := time.Parse(time.RFC3339, secData.ObjectMeta.CreationTimestamp.Format(time.RFC3339))
 	if err != nil {
 		return nil, errors.New("secret date is not valid")
@@ -93,9 +205,76 @@ func (k *K8SClient) GetSecret(ctx context.Context, secretName, namespace string)
 		val := string(value)
 		secretMap[key] = val
 	}
+	return &SecretData{Name: secData.Name, Namespace: secData.Namespace, Data: secretMap, LastUpdatedTime: lastUpdatedTime,
+		Labels: secData.Labels, Annotations: secData.Annotations}, nil
+}
 
-	k.log.Debugf("Secret %s fetched from namespace %s", secretName, namespace)
-	return &SecretData{Data: secretMap, LastUpdatedTime: lastUpdatedTime}, nil
+func (k *K8SClient) ListNamespaces(ctx context.Context, labelSelector string) ([]string, error) {
+	namespaces, err := k.client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list namespaces with selector %s", labelSelector)
+	}
+
+	names := []string{}
+	for _, ns := range namespaces.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+func (k *K8SClient) GetConfigMap(ctx context.Context, configMapName, namespace string) (*ConfigMapData, error) {
+	cm, err := k.client.CoreV1().ConfigMaps(namespace).Get(ctx, configMapName, metav1.GetOptions{})
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, ErrConfigMapNotFound
+		}
+		return nil, errors.WithMessagef(err, "error in reading configmap %s", configMapName)
+	}
+
+	k.log.Debugf("Configmap %s fetched from namespace %s", configMapName, namespace)
+	return toConfigMapData(cm)
+}
+
+// WatchConfigMaps watches the configmaps of the namespace matching the label selector,
+// only the named configmap when the name is set
+func (k *K8SClient) WatchConfigMaps(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error) {
+	w, err := k.client.CoreV1().ConfigMaps(namespace).Watch(ctx, watchListOptions(name, labelSelector))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to watch configmaps in namespace %s", namespace)
+	}
+	return w, nil
+}
+
+// ListConfigMaps returns the configmaps of the namespace matching the label selector and name prefix,
+// an empty selector or prefix matches all the configmaps
+func (k *K8SClient) ListConfigMaps(ctx context.Context, namespace, labelSelector, namePrefix string) ([]*ConfigMapData, error) {
+	cmList, err := k.client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to list configmaps in namespace %s", namespace)
+	}
+
+	configMaps := []*ConfigMapData{}
+	for i := range cmList.Items {
+		if !strings.HasPrefix(cmList.Items[i].Name, namePrefix) {
+			continue
+		}
+		configMap, err := toConfigMapData(&cmList.Items[i])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid configmap %s in namespace %s", cmList.Items[i].Name, namespace)
+		}
+		configMaps = append(configMaps, configMap)
+	}
+	k.log.Debugf("%d configmaps listed from namespace %s", len(configMaps), namespace)
+	return configMaps, nil
+}
+
+func toConfigMapData(cm *corev1.ConfigMap) (*ConfigMapData, error) {
+	lastUpdatedTime, err := time.Parse(time.RFC3339, cm.ObjectMeta.CreationTimestamp.Format(time.RFC3339))
+	if err != nil {
+		return nil, errors.New("configmap date is not valid")
+	}
+	return &ConfigMapData{Name: cm.Name, Namespace: cm.Namespace, Data: cm.Data, LastUpdatedTime: lastUpdatedTime,
+		Labels: cm.Labels, Annotations: cm.Annotations}, nil
 }
 
 func (k *K8SClient) GetConfigMapsHasPrefix(ctx context.Context, prefix string) ([]ConfigMapData, error) {
@@ -2,8 +2,13 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/hashicorp/vault/api"
@@ -19,10 +24,38 @@ const (
 	serviceTokenKey string = "service-token"
 )
 
+// ErrCredentialNotFound is returned when no credential exists at the requested path.
+var ErrCredentialNotFound = errors.New("credential not found")
+
+// OwnerMetadataKey is the custom metadata of the owner of the credentials written by the client
+const OwnerMetadataKey = "owner"
+
+const (
+	// ContentHashMetadataKey is the custom metadata of the sha256 of the credential data written by the client
+	ContentHashMetadataKey = "content_hash"
+	// ContentHashVersionMetadataKey is the KV v2 version the content hash was computed for,
+	// so a credential written by others since is not taken as unchanged
+	ContentHashVersionMetadataKey = "content_hash_version"
+)
+
+// ErrMetadataNotSupported is returned when the mount has no credential metadata, as for KV v1.
+var ErrMetadataNotSupported = errors.New("credential metadata not supported")
+
 type VaultClient struct {
-	c    *api.Client
-	conf config.VaultEnv
-	log  logging.Logger
+	c               *api.Client
+	conf            config.VaultEnv
+	log             logging.Logger
+	kvVersionsMutex sync.Mutex
+	kvVersions      map[string]int
+	cache           *CredentialCache
+	auth            *vaultAuth
+	renewal         *tokenRenewal
+	// mirrors are the clients of the other vault clusters the writes are repeated on
+	mirrors       []*VaultClient
+	writeObserver WriteObserver
+	// reader is the client of the read endpoint, created on the first read
+	reader      *api.Client
+	readerMutex sync.Mutex
 }
 
 func NewVaultClientForServiceAccount(ctx context.Context, log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
@@ -52,6 +85,12 @@ func NewVaultClientForVaultToken(log logging.Logger, conf config.VaultEnv) (*Vau
 		vc.c.SetToken(conf.VaultToken)
 		return vc, nil
 	}
+	if len(conf.VaultTokenFile) != 0 {
+		if err := vc.useTokenFile(conf.VaultTokenFile); err != nil {
+			return nil, err
+		}
+		return vc, nil
+	}
 
 	k8s, err := NewK8SClient(vc.log)
 	if err != nil {
@@ -70,37 +109,98 @@ func NewVaultClientForVaultToken(log logging.Logger, conf config.VaultEnv) (*Vau
 	return vc, nil
 }
 
+// insecureWarning logs the disabled TLS verification warning once per process
+var insecureWarning sync.Once
+
 func NewVaultClient(log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
 	cfg, err := prepareVaultConfig(conf)
 	if err != nil {
 		return nil, fmt.Errorf("error in vault config, %v", err)
 	}
+	if conf.CACert != "" && !conf.VaultTLSSkipVerify {
+		if err := configureCAReload(log, cfg, conf.CACert); err != nil {
+			return nil, err
+		}
+	}
 
 	c, err := api.NewClient(cfg)
 	if err != nil {
 		return nil, err
 	}
+	if conf.VaultNamespace != "" {
+		c.SetNamespace(conf.VaultNamespace)
+	}
+	if conf.VaultTLSSkipVerify {
+		insecureWarning.Do(func() {
+			log.Warn("vault TLS certificate verification is disabled, VAULT_TLS_SKIP_VERIFY must only be used for development")
+		})
+	}
 
 	return &VaultClient{
-		c:    c,
-		conf: conf,
-		log:  log,
+		c:          c,
+		conf:       conf,
+		log:        log,
+		kvVersions: map[string]int{},
 	}, nil
 }
 
+// VaultAddress returns the configured vault address, read from the address file when configured.
+// The file is read for every new client only, the clients kept across syncs compare the address
+// with the file to pick up a rotated address.
+func VaultAddress(conf config.VaultEnv) (string, error) {
+	if conf.VaultAddressFile == "" {
+		return conf.Address, nil
+	}
+	address, err := configValueOrFile("", conf.VaultAddressFile)
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to read vault address file %s", conf.VaultAddressFile)
+	}
+	return address, nil
+}
+
 func prepareVaultConfig(conf config.VaultEnv) (cfg *api.Config, err error) {
 	cfg = api.DefaultConfig()
-	cfg.Address = conf.Address
+	if cfg.Address, err = VaultAddress(conf); err != nil {
+		return nil, err
+	}
+	if cfg.Address == "" {
+		return nil, errors.New("vault address is required")
+	}
 	cfg.Timeout = conf.ReadTimeout
 	cfg.Backoff = retryablehttp.DefaultBackoff
+	cfg.CheckRetry = rateLimitRetryPolicy
 	cfg.MaxRetries = conf.MaxRetries
-	if conf.CACert != "" {
-		tlsConfig := api.TLSConfig{CACert: conf.CACert}
+	if err = ValidateTLSConfig(conf); err != nil {
+		return
+	}
+	tlsConfig := api.TLSConfig{
+		CACert:     conf.CACert,
+		ClientCert: conf.VaultClientCertFile,
+		ClientKey:  conf.VaultClientKeyFile,
+		Insecure:   conf.VaultTLSSkipVerify,
+	}
+	if tlsConfig.CACert != "" || tlsConfig.ClientCert != "" || tlsConfig.Insecure {
 		err = cfg.ConfigureTLS(&tlsConfig)
 	}
 	return
 }
 
+// ValidateTLSConfig verifies the vault client certificate and key are both configured
+// and load as a matching key pair, so a bad mTLS setup fails fast at startup
+func ValidateTLSConfig(conf config.VaultEnv) error {
+	if conf.VaultClientCertFile == "" && conf.VaultClientKeyFile == "" {
+		return nil
+	}
+	if conf.VaultClientCertFile == "" || conf.VaultClientKeyFile == "" {
+		return errors.New("both vault client certificate and key files are required for mTLS")
+	}
+	if _, err := tls.LoadX509KeyPair(conf.VaultClientCertFile, conf.VaultClientKeyFile); err != nil {
+		return errors.WithMessagef(err, "failed to load vault client certificate %s and key %s",
+			conf.VaultClientCertFile, conf.VaultClientKeyFile)
+	}
+	return nil
+}
+
 func (vc *VaultClient) configureAuthToken(ctx context.Context) (err error) {
 	metadata, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
@@ -136,47 +236,412 @@ func (vc *VaultClient) configureAuthToken(ctx context.Context) (err error) {
 }
 
 func (vc *VaultClient) GetCredential(ctx context.Context, mountPath, secretPath string) (cred map[string]string, err error) {
-	secretValByPath, err := vc.c.KVv2(mountPath).Get(context.Background(), secretPath)
+	cred, _, err = vc.GetCredentialWithVersion(ctx, mountPath, secretPath)
+	return
+}
+
+// GetCredentialWithVersion returns the credential along with its KV v2 version, to be used
+// as the expected version of PutCredentialCAS. The version is 0 for KV v1 mounts.
+func (vc *VaultClient) GetCredentialWithVersion(ctx context.Context, mountPath, secretPath string) (cred map[string]string, version int, err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if vc.cache != nil {
+		if entry, found := vc.cachedCredential(ctx, mountPath, secretPath); found {
+			return copyCredential(entry.cred), entry.version, nil
+		}
+	}
+
+	cred, version, err = vc.readCredential(ctx, vc.c, mountPath, secretPath)
+	if err == nil && vc.cache != nil {
+		vc.cache.put(credentialCacheKey(mountPath, secretPath), version, cred)
+	}
+	return
+}
+
+// readCredential reads the credential with its KV v2 version through the api client
+func (vc *VaultClient) readCredential(ctx context.Context, c *api.Client, mountPath, secretPath string) (cred map[string]string, version int, err error) {
+	secretValByPath, err := vc.kvGetFrom(ctx, c, mountPath, secretPath)
 	if err != nil {
-		err = errors.WithMessagef(err, "error in reading certificate data from %s", secretPath)
+		if errors.Is(err, api.ErrSecretNotFound) {
+			err = errors.WithMessagef(ErrCredentialNotFound, "no credential at %s", vc.secretPathRef(secretPath))
+			return
+		}
+		err = errors.WithMessagef(err, "error in reading certificate data from %s", vc.secretPathRef(secretPath))
 		return
 	}
 
 	if secretValByPath == nil {
-		err = errors.WithMessagef(err, "crdentaial not found at %s", secretPath)
+		err = errors.WithMessagef(ErrCredentialNotFound, "no credential at %s", vc.secretPathRef(secretPath))
 		return
 	}
 	if secretValByPath.Data == nil {
-		err = errors.WithMessagef(err, "crdentaial data is corrupted for %s", secretPath)
+		// the latest KV v2 version is soft deleted or destroyed
+		err = errors.WithMessagef(ErrCredentialNotFound, "credential at %s is deleted", vc.secretPathRef(secretPath))
 		return
 	}
 	cred = map[string]string{}
 	for key, val := range secretValByPath.Data {
-		cred[key] = val.(string)
+		strVal, ok := val.(string)
+		if !ok {
+			strVal = fmt.Sprintf("%v", val)
+		}
+		cred[key] = strVal
+	}
+
+	if secretValByPath.VersionMetadata != nil {
+		version = secretValByPath.VersionMetadata.Version
 	}
 	return
 }
 
-func (vc *VaultClient) PutCredential(ctx context.Context, mountPath, secretPath string, cred map[string]string) (err error) {
+// PutCredential writes the credential to the vault cluster of the client and then to the mirror clusters
+func (vc *VaultClient) PutCredential(ctx context.Context, mountPath, secretPath string, cred map[string]string) error {
+	if err := vc.PutPrimaryCredential(ctx, mountPath, secretPath, cred); err != nil {
+		return err
+	}
+	return vc.MirrorCredential(ctx, mountPath, secretPath, cred)
+}
+
+// PutPrimaryCredential writes the credential to the vault cluster of the client only, the mirror
+// clusters are written by MirrorCredential, so a failed write is retried without the mirror writes
+func (vc *VaultClient) PutPrimaryCredential(ctx context.Context, mountPath, secretPath string, cred map[string]string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if err = vc.checkProtection(ctx, mountPath, secretPath); err != nil {
+		return
+	}
+	credData := map[string]interface{}{}
+	for key, val := range cred {
+		credData[key] = val
+	}
+	written, err := vc.putCredentialData(ctx, mountPath, secretPath, credData)
+	if err != nil {
+		err = errors.WithMessagef(err, "error in putting credentail at %s", vc.secretPathRef(secretPath))
+		return
+	}
+	return vc.putWriteMetadata(ctx, mountPath, secretPath, cred, written)
+}
+
+// PutCredentialCAS writes the credential only if its current KV v2 version is the expected version,
+// an expected version 0 writes only if no credential exists yet. It fails with ErrCASMismatch when
+// the credential was changed since it was read, and is not supported for KV v1 mounts. The
+// mirror clusters are written without check-and-set once the write to the cluster succeeds.
+func (vc *VaultClient) PutCredentialCAS(ctx context.Context, mountPath, secretPath string, cred map[string]string, version int) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if err = vc.checkProtection(ctx, mountPath, secretPath); err != nil {
+		return
+	}
+	kvVersion, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if kvVersion == kvVersion1 {
+		return errors.Errorf("check-and-set is not supported for kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+
 	credData := map[string]interface{}{}
 	for key, val := range cred {
 		credData[key] = val
 	}
-	_, err = vc.c.KVv2(mountPath).Put(ctx, secretPath, credData)
+	written, err := vc.putCredentialData(ctx, mountPath, secretPath, credData, api.WithCheckAndSet(version))
 	if err != nil {
-		err = errors.WithMessagef(err, "error in putting credentail at %s", secretPath)
+		if isCASMismatch(err) {
+			return errors.WithMessagef(ErrCASMismatch, "credential at %s changed from version %d", vc.secretPathRef(secretPath), version)
+		}
+		err = errors.WithMessagef(err, "error in putting credentail at %s", vc.secretPathRef(secretPath))
+		return
 	}
-	return
+	if err = vc.putWriteMetadata(ctx, mountPath, secretPath, cred, written); err != nil {
+		return
+	}
+	return vc.MirrorCredential(ctx, mountPath, secretPath, cred)
+}
+
+// PatchCredential merges the given keys over the existing credential data. The write
+// uses the version read as check-and-set on KV v2 so concurrent updates are not lost, and it
+// fails with ErrCredentialNotFound when no credential exists at the path yet. The patched
+// credential is written to the mirror clusters once the write to the cluster succeeds.
+func (vc *VaultClient) PatchCredential(ctx context.Context, mountPath, secretPath string, cred map[string]string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if err = vc.checkProtection(ctx, mountPath, secretPath); err != nil {
+		return
+	}
+	existingSecret, err := vc.kvGet(ctx, mountPath, secretPath)
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return errors.WithMessagef(ErrCredentialNotFound, "no credential at %s", vc.secretPathRef(secretPath))
+		}
+		return errors.WithMessagef(err, "error in reading credentail at %s", vc.secretPathRef(secretPath))
+	}
+
+	credData := map[string]interface{}{}
+	for key, val := range existingSecret.Data {
+		credData[key] = val
+	}
+	for key, val := range cred {
+		credData[key] = val
+	}
+
+	version := 0
+	if existingSecret.VersionMetadata != nil {
+		version = existingSecret.VersionMetadata.Version
+	}
+	written, err := vc.putCredentialData(ctx, mountPath, secretPath, credData, api.WithCheckAndSet(version))
+	if err != nil {
+		if isCASMismatch(err) {
+			return errors.WithMessagef(ErrCASMismatch, "credential at %s changed from version %d", vc.secretPathRef(secretPath), version)
+		}
+		err = errors.WithMessagef(err, "error in patching credentail at %s", vc.secretPathRef(secretPath))
+		return
+	}
+
+	patchedCred := map[string]string{}
+	for key, val := range credData {
+		patchedCred[key] = fmt.Sprintf("%v", val)
+	}
+	if err = vc.putWriteMetadata(ctx, mountPath, secretPath, patchedCred, written); err != nil {
+		return
+	}
+	return vc.MirrorCredential(ctx, mountPath, secretPath, patchedCred)
+}
+
+// PutCredentialMetadata sets the KV v2 custom metadata of the credential, keeping the other metadata
+// fields. It fails with ErrMetadataNotSupported for KV v1 mounts which have no metadata.
+func (vc *VaultClient) PutCredentialMetadata(ctx context.Context, mountPath, secretPath string, customMetadata map[string]string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if version == kvVersion1 {
+		return errors.WithMessagef(ErrMetadataNotSupported, "kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+
+	metadata := map[string]interface{}{}
+	for key, val := range customMetadata {
+		metadata[key] = val
+	}
+	if err = vc.waitWriteLimit(ctx); err != nil {
+		return
+	}
+	err = vc.c.KVv2(mountPath).PatchMetadata(ctx, secretPath, api.KVMetadataPatchInput{CustomMetadata: metadata})
+	if err != nil {
+		err = errors.WithMessagef(err, "error in putting credentail metadata at %s", vc.secretPathRef(secretPath))
+		return
+	}
+	return vc.mirrorWrite(func(mirror *VaultClient) error {
+		return mirror.PutCredentialMetadata(ctx, mountPath, secretPath, customMetadata)
+	})
+}
+
+// putCredentialData writes the credential data to the vault cluster of the client, dropping the
+// cached credential and reporting the write to the write observer
+func (vc *VaultClient) putCredentialData(ctx context.Context, mountPath, secretPath string, credData map[string]interface{}, opts ...api.KVOption) (int, error) {
+	written, err := vc.kvPut(ctx, mountPath, secretPath, credData, opts...)
+	vc.InvalidateCachedCredential(mountPath, secretPath)
+	vc.observeWrite(err)
+	return written, err
+}
+
+// putWriteMetadata marks the KV v2 credential version written with the configured owner and
+// the content hash of the credential data, KV v1 has no metadata
+func (vc *VaultClient) putWriteMetadata(ctx context.Context, mountPath, secretPath string, cred map[string]string, version int) error {
+	if version == 0 {
+		return nil
+	}
+	customMetadata := map[string]interface{}{
+		ContentHashMetadataKey:        credentialHash(cred),
+		ContentHashVersionMetadataKey: strconv.Itoa(version),
+	}
+	if vc.conf.CredentialOwner != "" {
+		customMetadata[OwnerMetadataKey] = vc.conf.CredentialOwner
+	}
+	if err := vc.waitWriteLimit(ctx); err != nil {
+		return err
+	}
+	err := vc.c.KVv2(mountPath).PatchMetadata(ctx, secretPath, api.KVMetadataPatchInput{CustomMetadata: customMetadata})
+	if err != nil {
+		return errors.WithMessagef(err, "error in putting credentail metadata at %s", vc.secretPathRef(secretPath))
+	}
+	return nil
+}
+
+// ConfigureMaxVersions sets the number of KV v2 versions kept for the credential,
+// 0 keeps the number of versions configured for the mount
+func (vc *VaultClient) ConfigureMaxVersions(ctx context.Context, mountPath, secretPath string, maxVersions int) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if version == kvVersion1 {
+		return errors.WithMessagef(ErrMetadataNotSupported, "kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+
+	if err = vc.waitWriteLimit(ctx); err != nil {
+		return
+	}
+	err = vc.c.KVv2(mountPath).PatchMetadata(ctx, secretPath, api.KVMetadataPatchInput{MaxVersions: &maxVersions})
+	if err != nil {
+		err = errors.WithMessagef(err, "error in configuring max versions of credentail at %s", vc.secretPathRef(secretPath))
+		return
+	}
+	return vc.mirrorWrite(func(mirror *VaultClient) error {
+		return mirror.ConfigureMaxVersions(ctx, mountPath, secretPath, maxVersions)
+	})
+}
+
+// ConfigureDeleteVersionAfter sets the time after which the KV v2 versions of the credential are
+// deleted by vault, 0 keeps the versions until deleted. The metadata patch needs vault 1.9 or later.
+func (vc *VaultClient) ConfigureDeleteVersionAfter(ctx context.Context, mountPath, secretPath string, ttl time.Duration) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if version == kvVersion1 {
+		return errors.WithMessagef(ErrMetadataNotSupported, "kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+
+	if err = vc.waitWriteLimit(ctx); err != nil {
+		return
+	}
+	err = vc.c.KVv2(mountPath).PatchMetadata(ctx, secretPath, api.KVMetadataPatchInput{DeleteVersionAfter: &ttl})
+	if err != nil {
+		err = errors.WithMessagef(err, "error in configuring delete version after of credentail at %s", vc.secretPathRef(secretPath))
+		return
+	}
+	return vc.mirrorWrite(func(mirror *VaultClient) error {
+		return mirror.ConfigureDeleteVersionAfter(ctx, mountPath, secretPath, ttl)
+	})
 }
 
+// DeleteCredential permanently removes the credential with all of its versions
+// by deleting its KV v2 metadata, DELETE /<mount>/metadata/<path>.
+// For KV v1 the secret is deleted, DELETE /<mount>/<path>.
 func (vc *VaultClient) DeleteCredential(ctx context.Context, mountPath, secretPath string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if err = vc.checkCredentialExists(ctx, mountPath, secretPath); err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if err = vc.waitWriteLimit(ctx); err != nil {
+		return
+	}
+	if version == kvVersion1 {
+		err = vc.c.KVv1(mountPath).Delete(ctx, secretPath)
+	} else {
+		err = vc.c.KVv2(mountPath).DeleteMetadata(ctx, secretPath)
+	}
+	vc.InvalidateCachedCredential(mountPath, secretPath)
+	if err != nil {
+		err = errors.WithMessagef(err, "error in deleting credentail at %s", vc.secretPathRef(secretPath))
+		return
+	}
+	return vc.mirrorWrite(func(mirror *VaultClient) error {
+		if err := mirror.DeleteCredential(ctx, mountPath, secretPath); err != nil && !errors.Is(err, ErrCredentialNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// SoftDeleteCredential marks only the latest version of the credential as deleted,
+// DELETE /<mount>/data/<path>. Older versions are retained and it can be undeleted.
+func (vc *VaultClient) SoftDeleteCredential(ctx context.Context, mountPath, secretPath string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+	if version == kvVersion1 {
+		return errors.Errorf("soft delete is not supported for kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+	if err = vc.checkCredentialExists(ctx, mountPath, secretPath); err != nil {
+		return
+	}
+	if err = vc.waitWriteLimit(ctx); err != nil {
+		return
+	}
 	err = vc.c.KVv2(mountPath).Delete(ctx, secretPath)
+	vc.InvalidateCachedCredential(mountPath, secretPath)
 	if err != nil {
-		err = errors.WithMessagef(err, "error in deleting credentail at %s", secretPath)
+		err = errors.WithMessagef(err, "error in soft deleting credentail at %s", vc.secretPathRef(secretPath))
 	}
 	return
 }
 
+func (vc *VaultClient) checkCredentialExists(ctx context.Context, mountPath, secretPath string) error {
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return err
+	}
+	if version == kvVersion1 {
+		_, err = vc.c.KVv1(mountPath).Get(ctx, secretPath)
+	} else {
+		_, err = vc.c.KVv2(mountPath).GetMetadata(ctx, secretPath)
+	}
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return errors.WithMessagef(ErrCredentialNotFound, "no credential at %s", vc.secretPathRef(secretPath))
+		}
+		return errors.WithMessagef(err, "error in reading credentail metadata at %s", vc.secretPathRef(secretPath))
+	}
+	return nil
+}
+
+// CheckConnectivity verifies the vault token authenticates and the mounts exist, without any writes
+func (vc *VaultClient) CheckConnectivity(ctx context.Context, mountPaths ...string) error {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return err
+	}
+	if _, err := vc.c.Auth().Token().LookupSelfWithContext(ctx); err != nil {
+		return errors.WithMessagef(err, "failed to authenticate with vault%s", vc.namespaceRef())
+	}
+
+	mounts, err := vc.c.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to list vault mounts%s", vc.namespaceRef())
+	}
+	for _, mountPath := range mountPaths {
+		if _, found := mounts[strings.Trim(mountPath, "/")+"/"]; !found {
+			return errors.Errorf("vault mount %s not found", vc.secretPathRef(mountPath))
+		}
+	}
+	return nil
+}
+
+// secretPathRef describes the secret path for error messages, including the vault namespace when set
+func (vc *VaultClient) secretPathRef(secretPath string) string {
+	return secretPath + vc.namespaceRef()
+}
+
+func (vc *VaultClient) namespaceRef() string {
+	if vc.conf.VaultNamespace == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in namespace %s", vc.conf.VaultNamespace)
+}
+
 func (vc *VaultClient) JoinRaftCluster(leaderAddress string) error {
 	req := &api.RaftJoinRequest{
 		Retry:         true,
@@ -0,0 +1,156 @@
+package client
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/pkg/errors"
+)
+
+const (
+	AuthMethodToken   = "token"
+	AuthMethodAppRole = "approle"
+
+	// reauthLeaseFraction of the token lease is used before logging in again
+	reauthLeaseFraction = 0.8
+)
+
+// vaultLogin authenticates with vault and returns the auth info of the new token
+type vaultLogin func(ctx context.Context) (*api.SecretAuth, error)
+
+// vaultAuth holds the login of the auth method, so the client logs in again before the token lease expires
+type vaultAuth struct {
+	mutex    sync.Mutex
+	login    vaultLogin
+	reauthAt time.Time
+	// maxTokenAge makes the client login again after the age even when the token lease is longer
+	maxTokenAge time.Duration
+}
+
+// NewVaultClientForAuthMethod returns a vault client authenticated with the configured auth method,
+// with the background token renewal started. The client must be closed to stop the renewal.
+func NewVaultClientForAuthMethod(log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
+	if len(conf.VaultFallbackAddresses) != 0 {
+		return newVaultClusterClient(log, conf)
+	}
+	return newVaultClientForAuthMethod(log, conf)
+}
+
+func newVaultClientForAuthMethod(log logging.Logger, conf config.VaultEnv) (vc *VaultClient, err error) {
+	switch conf.VaultAuthMethod {
+	case AuthMethodToken, "":
+		vc, err = NewVaultClientForVaultToken(log, conf)
+	case AuthMethodAppRole:
+		vc, err = NewVaultClientForAppRole(log, conf)
+	case AuthMethodKubernetes:
+		vc, err = NewVaultClientForKubernetesAuth(log, conf)
+	default:
+		return nil, errors.Errorf("vault auth method %s not supported", conf.VaultAuthMethod)
+	}
+	if err != nil {
+		return nil, err
+	}
+	vc.startTokenRenewal()
+	return vc, nil
+}
+
+// NewVaultClientForAppRole returns a vault client logged in with the AppRole role id and secret id,
+// read from the config or from the configured files. The client logs in again when the token lease nears expiry.
+func NewVaultClientForAppRole(log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
+	vc, err := NewVaultClient(log, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	roleID, err := configValueOrFile(conf.AppRoleID, conf.AppRoleIDFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read approle role id")
+	}
+	secretID, err := configValueOrFile(conf.AppRoleSecretID, conf.AppRoleSecretIDFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to read approle secret id")
+	}
+	if roleID == "" || secretID == "" {
+		return nil, errors.New("approle role id and secret id are required")
+	}
+
+	loginPath := "auth/" + strings.Trim(conf.AppRoleMountPath, "/") + "/login"
+	vc.auth = &vaultAuth{login: func(ctx context.Context) (*api.SecretAuth, error) {
+		secret, err := vc.c.Logical().WriteWithContext(ctx, loginPath, map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "error in login with approle%s", vc.namespaceRef())
+		}
+		if secret == nil || secret.Auth == nil {
+			return nil, errors.New("no auth info was returned after approle login")
+		}
+		return secret.Auth, nil
+	}}
+
+	if err := vc.authenticate(context.Background()); err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
+
+func configValueOrFile(value, filePath string) (string, error) {
+	if value != "" || filePath == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// authenticate logs in with the auth method and sets the new token on the client
+func (vc *VaultClient) authenticate(ctx context.Context) error {
+	vc.auth.mutex.Lock()
+	defer vc.auth.mutex.Unlock()
+	return vc.loginLocked(ctx)
+}
+
+func (vc *VaultClient) loginLocked(ctx context.Context) error {
+	authInfo, err := vc.auth.login(ctx)
+	if err != nil {
+		return err
+	}
+	vc.c.SetToken(authInfo.ClientToken)
+
+	vc.auth.reauthAt = time.Time{}
+	if authInfo.LeaseDuration > 0 {
+		lease := time.Duration(float64(authInfo.LeaseDuration)*reauthLeaseFraction) * time.Second
+		vc.auth.reauthAt = time.Now().Add(lease)
+	}
+	if maxAge := vc.auth.maxTokenAge; maxAge > 0 && (vc.auth.reauthAt.IsZero() || time.Until(vc.auth.reauthAt) > maxAge) {
+		vc.auth.reauthAt = time.Now().Add(maxAge)
+	}
+	vc.log.Debugf("logged in to vault, token lease %ds", authInfo.LeaseDuration)
+	return nil
+}
+
+// ensureAuth logs in again when the token of the auth method nears its lease expiry,
+// it is a no-op for static tokens
+func (vc *VaultClient) ensureAuth(ctx context.Context) error {
+	if vc.auth == nil {
+		return nil
+	}
+	vc.auth.mutex.Lock()
+	defer vc.auth.mutex.Unlock()
+	if vc.auth.reauthAt.IsZero() || time.Now().Before(vc.auth.reauthAt) {
+		return nil
+	}
+	if err := vc.loginLocked(ctx); err != nil {
+		return errors.WithMessage(err, "failed to renew vault login")
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/intelops/go-common/logging"
+	"github.com/pkg/errors"
+)
+
+// caReloaders holds the ca reloader of every vault ca certificate file, shared by the clients
+var (
+	caReloaders      = map[string]*caReloader{}
+	caReloadersMutex sync.Mutex
+)
+
+// caReloader reloads the ca certificate file when its modification time or size changes,
+// so the TLS handshakes verify the vault server certificate with the rotated ca without a restart
+type caReloader struct {
+	log     logging.Logger
+	file    string
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	pool    *x509.CertPool
+}
+
+// configureCAReload verifies the vault server certificate on every TLS handshake with the current ca
+// certificate file instead of the ca pool loaded when the client was created
+func configureCAReload(log logging.Logger, cfg *api.Config, caFile string) error {
+	transport, ok := cfg.HttpClient.Transport.(*http.Transport)
+	if !ok || transport.TLSClientConfig == nil {
+		return nil
+	}
+
+	caReloadersMutex.Lock()
+	reloader, found := caReloaders[caFile]
+	if !found {
+		reloader = &caReloader{log: log, file: caFile}
+		caReloaders[caFile] = reloader
+	}
+	caReloadersMutex.Unlock()
+	if _, err := reloader.certPool(); err != nil {
+		return err
+	}
+
+	// the server certificate is verified by VerifyConnection against the reloaded ca pool
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.TLSClientConfig.VerifyConnection = reloader.verifyConnection
+	return nil
+}
+
+func (r *caReloader) verifyConnection(cs tls.ConnectionState) error {
+	pool, err := r.certPool()
+	if err != nil {
+		return err
+	}
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("vault server presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		DNSName:       cs.ServerName,
+	})
+	return err
+}
+
+// certPool returns the ca pool of the ca certificate file, loaded again when the file changed
+func (r *caReloader) certPool() (*x509.CertPool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	info, err := os.Stat(r.file)
+	if err != nil {
+		if r.pool != nil {
+			// a ca file being replaced is briefly missing, the loaded pool is kept meanwhile
+			return r.pool, nil
+		}
+		return nil, errors.WithMessagef(err, "failed to read vault ca certificate file %s", r.file)
+	}
+	if r.pool != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return r.pool, nil
+	}
+
+	data, err := os.ReadFile(r.file)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to read vault ca certificate file %s", r.file)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		if r.pool != nil {
+			r.log.Warn(fmt.Sprintf("vault ca certificate file %s has no valid certificate, keeping the loaded ca", r.file))
+			return r.pool, nil
+		}
+		return nil, errors.Errorf("vault ca certificate file %s has no valid certificate", r.file)
+	}
+	if r.pool != nil {
+		r.log.Infof("reloaded vault ca certificate file %s", r.file)
+	}
+	r.pool, r.modTime, r.size = pool, info.ModTime(), info.Size()
+	return pool, nil
+}
@@ -0,0 +1,207 @@
+package client
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// CredentialCache is an LRU cache of the credentials read from vault, keyed by mount and secret path.
+// A cached credential is served without a vault read until its ttl expires, after which it is kept
+// as long as the KV v2 version metadata is unchanged.
+type CredentialCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type credentialCacheEntry struct {
+	key       string
+	version   int
+	hash      string
+	cred      map[string]string
+	expiresAt time.Time
+}
+
+func NewCredentialCache(size int, ttl time.Duration) *CredentialCache {
+	return &CredentialCache{
+		size:    size,
+		ttl:     ttl,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+func (c *CredentialCache) get(key string) (credentialCacheEntry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	elem, found := c.entries[key]
+	if !found {
+		return credentialCacheEntry{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return *elem.Value.(*credentialCacheEntry), true
+}
+
+func (c *CredentialCache) put(key string, version int, cred map[string]string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := &credentialCacheEntry{key: key, version: version, hash: credentialHash(cred),
+		cred: copyCredential(cred), expiresAt: time.Now().Add(c.ttl)}
+	if elem, found := c.entries[key]; found {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*credentialCacheEntry).key)
+	}
+}
+
+func (c *CredentialCache) extend(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, found := c.entries[key]; found {
+		elem.Value.(*credentialCacheEntry).expiresAt = time.Now().Add(c.ttl)
+	}
+}
+
+func (c *CredentialCache) invalidate(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if elem, found := c.entries[key]; found {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// SetCredentialCache enables caching the credentials read by the client, the cache
+// is shared across clients so it outlives the client of a single sync run
+func (vc *VaultClient) SetCredentialCache(cache *CredentialCache) {
+	vc.cache = cache
+}
+
+// InvalidateCachedCredential drops the cached credential, so the next read goes to vault
+func (vc *VaultClient) InvalidateCachedCredential(mountPath, secretPath string) {
+	if vc.cache != nil {
+		vc.cache.invalidate(credentialCacheKey(mountPath, secretPath))
+	}
+}
+
+// IsCredentialUnchanged reports whether vault holds the same credential data at the path,
+// comparing the content hash with the cached credential when available, else with the content
+// hash in the KV v2 metadata before reading the credential. The reads go to the read endpoint
+// when configured. The credential found is cached by its version, as by GetCredential. Only the
+// vault cluster of the client is compared, the mirror clusters are compared by MirrorCredential.
+func (vc *VaultClient) IsCredentialUnchanged(ctx context.Context, mountPath, secretPath string, cred map[string]string) bool {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return false
+	}
+	if vc.cache != nil {
+		if entry, found := vc.cachedCredential(ctx, mountPath, secretPath); found {
+			return entry.hash == credentialHash(cred)
+		}
+	}
+	unchanged, known, version := vc.contentHashUnchanged(ctx, mountPath, secretPath, cred)
+	if known {
+		if unchanged && vc.cache != nil {
+			// the credential holds the same data as the version of the content hash
+			vc.cache.put(credentialCacheKey(mountPath, secretPath), version, cred)
+		}
+		return unchanged
+	}
+	existingCred, version, err := vc.readCredential(ctx, vc.readClient(), mountPath, secretPath)
+	if err != nil {
+		return false
+	}
+	if vc.cache != nil {
+		vc.cache.put(credentialCacheKey(mountPath, secretPath), version, existingCred)
+	}
+	return credentialHash(existingCred) == credentialHash(cred)
+}
+
+// cachedCredential returns the cached credential while its ttl is valid, or when expired
+// while the current version in the KV v2 metadata still matches the cached version
+func (vc *VaultClient) cachedCredential(ctx context.Context, mountPath, secretPath string) (credentialCacheEntry, bool) {
+	key := credentialCacheKey(mountPath, secretPath)
+	entry, found := vc.cache.get(key)
+	if !found {
+		return entry, false
+	}
+	if time.Now().Before(entry.expiresAt) {
+		return entry, true
+	}
+
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil || version != kvVersion2 || entry.version == 0 {
+		return entry, false
+	}
+	metadata, err := vc.readClient().KVv2(mountPath).GetMetadata(ctx, secretPath)
+	if err != nil || metadata.CurrentVersion != entry.version {
+		return entry, false
+	}
+	vc.cache.extend(key)
+	return entry, true
+}
+
+// contentHashUnchanged compares the credential with the content hash in the KV v2 metadata, the
+// hash is known only when it was written for the current version of the credential, which is returned
+func (vc *VaultClient) contentHashUnchanged(ctx context.Context, mountPath, secretPath string, cred map[string]string) (bool, bool, int) {
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil || version != kvVersion2 {
+		return false, false, 0
+	}
+	metadata, err := vc.readClient().KVv2(mountPath).GetMetadata(ctx, secretPath)
+	if err != nil {
+		return false, errors.Is(err, api.ErrSecretNotFound), 0
+	}
+	hash, _ := metadata.CustomMetadata[ContentHashMetadataKey].(string)
+	hashVersion, _ := metadata.CustomMetadata[ContentHashVersionMetadataKey].(string)
+	if hash == "" || hashVersion != strconv.Itoa(metadata.CurrentVersion) {
+		return false, false, 0
+	}
+	return hash == credentialHash(cred), true, metadata.CurrentVersion
+}
+
+func credentialCacheKey(mountPath, secretPath string) string {
+	return mountPath + "/" + secretPath
+}
+
+func credentialHash(cred map[string]string) string {
+	keys := make([]string, 0, len(cred))
+	for key := range cred {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(cred[key]))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func copyCredential(cred map[string]string) map[string]string {
+	credCopy := make(map[string]string, len(cred))
+	for key, val := range cred {
+		credCopy[key] = val
+	}
+	return credCopy
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+)
+
+func TestIsCredentialUnchangedFillsCache(t *testing.T) {
+	cred := map[string]string{"password": "s3cret"}
+	tests := []struct {
+		name string
+		// written by the client, so the content hash is in the metadata
+		written       bool
+		wantDataReads int
+	}{
+		{name: "credential read", wantDataReads: 1},
+		{name: "content hash compared", written: true, wantDataReads: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+			ctx := context.Background()
+			if tt.written {
+				writer := newTestVaultClient(t, server, config.VaultEnv{})
+				if err := writer.PutCredential(ctx, "secret", "app/db", cred); err != nil {
+					t.Fatalf("PutCredential() error = %v", err)
+				}
+			} else {
+				server.Seed("secret", "app/db", map[string]interface{}{"password": "s3cret"})
+			}
+			vc := newTestVaultClient(t, server, config.VaultEnv{})
+			vc.SetCredentialCache(NewCredentialCache(10, time.Hour))
+
+			for i := 0; i < 2; i++ {
+				if !vc.IsCredentialUnchanged(ctx, "secret", "app/db", cred) {
+					t.Fatalf("IsCredentialUnchanged() = false for the same credential")
+				}
+			}
+			got, version, err := vc.GetCredentialWithVersion(ctx, "secret", "app/db")
+			if err != nil {
+				t.Fatalf("GetCredentialWithVersion() error = %v", err)
+			}
+			if got["password"] != "s3cret" || version != 1 {
+				t.Errorf("GetCredentialWithVersion() = %v, %d", got, version)
+			}
+			if reads := server.RequestCount("GET", "secret/data/app/db"); reads != tt.wantDataReads {
+				t.Errorf("credential read %d times, want %d with the cache filled by the change detection", reads, tt.wantDataReads)
+			}
+			if vc.IsCredentialUnchanged(ctx, "secret", "app/db", map[string]string{"password": "changed"}) {
+				t.Errorf("IsCredentialUnchanged() = true for a changed credential")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/pkg/errors"
+)
+
+// WriteObserver is notified of the outcome of every credential write, by vault cluster address
+type WriteObserver func(address string, err error)
+
+// newVaultClusterClient returns a client of the first reachable vault cluster of the vault address
+// followed by the fallback addresses. With mirror writes enabled the clients of the other reachable
+// clusters are kept as mirrors, and every credential put, metadata write and delete is repeated on them.
+func newVaultClusterClient(log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
+	var (
+		errs    *multierror.Error
+		clients []*VaultClient
+	)
+	for i, clusterConf := range vaultClusterConfigs(conf) {
+		vc, err := newVaultClientForAuthMethod(log, clusterConf)
+		if err == nil {
+			if err = vc.CheckReachable(context.Background()); err != nil {
+				vc.Close()
+			}
+		}
+		if err != nil {
+			log.Warn(fmt.Sprintf("vault cluster %d is not available, %s", i, err))
+			errs = multierror.Append(errs, err)
+			continue
+		}
+
+		if !conf.MirrorWrites {
+			if i > 0 {
+				log.Warn(fmt.Sprintf("failed over to vault cluster %s", vc.Address()))
+			}
+			return vc, nil
+		}
+		clients = append(clients, vc)
+	}
+
+	if len(clients) == 0 {
+		return nil, errors.WithMessage(errs.ErrorOrNil(), "no vault cluster is reachable")
+	}
+	vc := clients[0]
+	vc.mirrors = clients[1:]
+	return vc, nil
+}
+
+// vaultClusterConfigs returns the config of every vault cluster, the configured vault first
+func vaultClusterConfigs(conf config.VaultEnv) []config.VaultEnv {
+	confs := []config.VaultEnv{conf}
+	for _, address := range conf.VaultFallbackAddresses {
+		clusterConf := conf
+		clusterConf.Address = address
+		clusterConf.VaultAddressFile = ""
+		clusterConf.VaultReadAddress = ""
+		clusterConf.VaultFallbackAddresses = nil
+		confs = append(confs, clusterConf)
+	}
+	return confs
+}
+
+// CheckReachable verifies the vault cluster responds as initialized and unsealed
+func (vc *VaultClient) CheckReachable(ctx context.Context) error {
+	if _, err := vc.c.Sys().HealthWithContext(ctx); err != nil {
+		return errors.WithMessagef(err, "vault cluster %s is not reachable", vc.Address())
+	}
+	return nil
+}
+
+// Address returns the address of the vault cluster of the client
+func (vc *VaultClient) Address() string {
+	return vc.c.Address()
+}
+
+// SetWriteObserver sets the observer of the credential writes to the vault cluster and its mirrors
+func (vc *VaultClient) SetWriteObserver(observer WriteObserver) {
+	vc.writeObserver = observer
+	for _, mirror := range vc.mirrors {
+		mirror.writeObserver = observer
+	}
+}
+
+func (vc *VaultClient) observeWrite(err error) {
+	if vc.writeObserver != nil {
+		vc.writeObserver(vc.Address(), err)
+	}
+}
+
+// MirrorCredential writes the credential to the mirror clusters which do not hold the same
+// credential data, each mirror is compared separately so an up to date mirror is not written
+func (vc *VaultClient) MirrorCredential(ctx context.Context, mountPath, secretPath string, cred map[string]string) error {
+	return vc.mirrorWrite(func(mirror *VaultClient) error {
+		if mirror.IsCredentialUnchanged(ctx, mountPath, secretPath, cred) {
+			return nil
+		}
+		return mirror.PutCredential(ctx, mountPath, secretPath, cred)
+	})
+}
+
+// mirrorWrite repeats the write on every mirror cluster and aggregates the mirror failures
+func (vc *VaultClient) mirrorWrite(write func(mirror *VaultClient) error) error {
+	var errs *multierror.Error
+	for _, mirror := range vc.mirrors {
+		if err := write(mirror); err != nil {
+			errs = multierror.Append(errs, errors.WithMessagef(err, "mirror write to vault cluster %s failed", mirror.Address()))
+		}
+	}
+	return errs.ErrorOrNil()
+}
@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+)
+
+// newMirroredTestVaultClient returns a client of the primary test vault server with the mirrors as mirror clusters
+func newMirroredTestVaultClient(t *testing.T, primary *vaulttest.Server, mirrors ...*vaulttest.Server) *VaultClient {
+	vc := newTestVaultClient(t, primary, config.VaultEnv{})
+	for _, mirror := range mirrors {
+		vc.mirrors = append(vc.mirrors, newTestVaultClient(t, mirror, config.VaultEnv{}))
+	}
+	return vc
+}
+
+func TestMirrorCredential(t *testing.T) {
+	cred := map[string]string{"password": "s3cret"}
+	tests := []struct {
+		name        string
+		mirrorCreds []map[string]interface{}
+		wantWrites  []int
+	}{
+		{name: "mirrors up to date", mirrorCreds: []map[string]interface{}{{"password": "s3cret"}, {"password": "s3cret"}}, wantWrites: []int{0, 0}},
+		{name: "one mirror missing", mirrorCreds: []map[string]interface{}{{"password": "s3cret"}, nil}, wantWrites: []int{0, 1}},
+		{name: "one mirror stale", mirrorCreds: []map[string]interface{}{{"password": "old"}, {"password": "s3cret"}}, wantWrites: []int{1, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+			mirrors := []*vaulttest.Server{}
+			for _, mirrorCred := range tt.mirrorCreds {
+				mirror := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+				if mirrorCred != nil {
+					mirror.Seed("secret", "generic/payments/db", mirrorCred)
+				}
+				mirrors = append(mirrors, mirror)
+			}
+			vc := newMirroredTestVaultClient(t, primary, mirrors...)
+			ctx := context.Background()
+
+			if err := vc.PutCredential(ctx, "secret", "generic/payments/db", cred); err != nil {
+				t.Fatalf("PutCredential() error = %v", err)
+			}
+			if !vc.IsCredentialUnchanged(ctx, "secret", "generic/payments/db", cred) {
+				t.Errorf("IsCredentialUnchanged() = false for the credential just written to the primary cluster")
+			}
+			for i, mirror := range mirrors {
+				if got := mirror.RequestCount("PUT", "secret/data/generic/payments/db"); got != tt.wantWrites[i] {
+					t.Errorf("mirror %d written %d times, want %d", i, got, tt.wantWrites[i])
+				}
+				if data, _ := mirror.Latest("secret", "generic/payments/db"); data["password"] != "s3cret" {
+					t.Errorf("mirror %d holds %v after the write", i, data)
+				}
+			}
+
+			// the mirrors are now up to date, so mirroring again writes nothing
+			if err := vc.MirrorCredential(ctx, "secret", "generic/payments/db", cred); err != nil {
+				t.Fatalf("MirrorCredential() error = %v", err)
+			}
+			for i, mirror := range mirrors {
+				if got := mirror.RequestCount("PUT", "secret/data/generic/payments/db"); got != tt.wantWrites[i] {
+					t.Errorf("mirror %d written %d times after mirroring again, want %d", i, got, tt.wantWrites[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPutPrimaryCredential(t *testing.T) {
+	primary := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+	mirror := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+	vc := newMirroredTestVaultClient(t, primary, mirror)
+	ctx := context.Background()
+	cred := map[string]string{"password": "s3cret"}
+
+	if err := vc.PutPrimaryCredential(ctx, "secret", "generic/payments/db", cred); err != nil {
+		t.Fatalf("PutPrimaryCredential() error = %v", err)
+	}
+	if data, _ := primary.Latest("secret", "generic/payments/db"); data["password"] != "s3cret" {
+		t.Errorf("primary holds %v after the write", data)
+	}
+	if got := mirror.RequestCount("PUT", "secret/data/generic/payments/db"); got != 0 {
+		t.Errorf("mirror written %d times by the primary put, want 0", got)
+	}
+
+	if err := vc.MirrorCredential(ctx, "secret", "generic/payments/db", cred); err != nil {
+		t.Fatalf("MirrorCredential() error = %v", err)
+	}
+	if data, _ := mirror.Latest("secret", "generic/payments/db"); data["password"] != "s3cret" {
+		t.Errorf("mirror holds %v after mirroring", data)
+	}
+}
+
+func TestConditionalWritePostWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, vc *VaultClient) error
+	}{
+		{name: "check-and-set put", write: func(ctx context.Context, vc *VaultClient) error {
+			return vc.PutCredentialCAS(ctx, "secret", "generic/payments/db", map[string]string{"password": "new"}, 1)
+		}},
+		{name: "patch", write: func(ctx context.Context, vc *VaultClient) error {
+			return vc.PatchCredential(ctx, "secret", "generic/payments/db", map[string]string{"password": "new"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+			mirror := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+			primary.Seed("secret", "generic/payments/db", map[string]interface{}{"password": "old"})
+			vc := newMirroredTestVaultClient(t, primary, mirror)
+			vc.SetCredentialCache(NewCredentialCache(10, time.Hour))
+			observed := []string{}
+			vc.SetWriteObserver(func(address string, err error) {
+				if err != nil {
+					t.Errorf("write to %s observed as failed, %v", address, err)
+				}
+				observed = append(observed, address)
+			})
+			ctx := context.Background()
+
+			if _, err := vc.GetCredential(ctx, "secret", "generic/payments/db"); err != nil {
+				t.Fatalf("GetCredential() error = %v", err)
+			}
+			if err := tt.write(ctx, vc); err != nil {
+				t.Fatalf("write error = %v", err)
+			}
+
+			cred, err := vc.GetCredential(ctx, "secret", "generic/payments/db")
+			if err != nil {
+				t.Fatalf("GetCredential() error = %v", err)
+			}
+			if cred["password"] != "new" {
+				t.Errorf("GetCredential() = %v after the write, cached credential not invalidated", cred)
+			}
+			if data, _ := mirror.Latest("secret", "generic/payments/db"); data["password"] != "new" {
+				t.Errorf("mirror holds %v after the write", data)
+			}
+			if len(observed) != 2 || observed[0] != primary.URL() || observed[1] != mirror.URL() {
+				t.Errorf("observed writes to %v, want the primary and the mirror", observed)
+			}
+		})
+	}
+}
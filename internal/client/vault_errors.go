@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// ErrCASMismatch is returned when a check-and-set write finds the credential version changed
+var ErrCASMismatch = errors.New("credential version changed since it was read")
+
+// isCASMismatch reports whether vault rejected the write as the check-and-set version did not match
+func isCASMismatch(err error) bool {
+	var respErr *api.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	for _, errMsg := range respErr.Errors {
+		if strings.Contains(errMsg, "check-and-set") {
+			return true
+		}
+	}
+	return false
+}
+
+// RateLimitedError is returned when vault rejects a request with 429 too many requests and a
+// Retry-After header, the request is not retried by the api client so that the retry of the
+// operation waits the RetryAfter requested by vault
+type RateLimitedError struct {
+	URL        string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("vault rate limit quota exceeded for %s, retry after %s", e.URL, e.RetryAfter)
+}
+
+// rateLimitRetryPolicy is the api client retry policy returning a RateLimitedError for
+// the 429 responses with a Retry-After header, other responses use the default policy
+func rateLimitRetryPolicy(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() == nil && err == nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return false, &RateLimitedError{URL: resp.Request.URL.Path, RetryAfter: retryAfter}
+		}
+	}
+	return api.DefaultRetryPolicy(ctx, resp, err)
+}
+
+// parseRetryAfter parses the Retry-After header given as seconds or as an http date
+func parseRetryAfter(retryAfter string, now time.Time) (time.Duration, bool) {
+	if retryAfter == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	retryTime, err := http.ParseTime(retryAfter)
+	if err != nil {
+		return 0, false
+	}
+	if wait := retryTime.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+// TimeoutError is returned when a vault operation does not complete within its timeout
+type TimeoutError struct {
+	Operation string
+	Timeout   time.Duration
+	Err       error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("vault %s timed out after %s, %v", e.Operation, e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryableError reports whether the vault request failed with a transient error.
+// Network failures and 5xx responses, including 503 while vault is sealed, are retryable,
+// operation timeouts and 429 rate limited requests are retryable. Other 4xx responses like
+// permission denied, cancelled contexts and the errors of the client itself, like a protected
+// or missing credential, are permanent and not retried.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return true
+	}
+	var rateLimitedErr *RateLimitedError
+	if errors.As(err, &rateLimitedErr) {
+		return true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var respErr *api.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode >= 500 || respErr.StatusCode == http.StatusTooManyRequests
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/pkg/errors"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		retryAfter string
+		want       time.Duration
+		wantOK     bool
+	}{
+		{name: "seconds", retryAfter: "1", want: time.Second, wantOK: true},
+		{name: "zero seconds", retryAfter: "0", want: 0, wantOK: true},
+		{name: "negative seconds", retryAfter: "-5", wantOK: false},
+		{name: "http date", retryAfter: "Thu, 01 Jun 2023 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{name: "http date in the past", retryAfter: "Thu, 01 Jun 2023 11:59:00 GMT", want: 0, wantOK: true},
+		{name: "empty", retryAfter: "", wantOK: false},
+		{name: "garbage", retryAfter: "soon", wantOK: false},
+		{name: "fractional seconds", retryAfter: "1.5", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.retryAfter, now)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.retryAfter, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRateLimitRetryPolicy(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"password":"secret"}}`))
+	}))
+	defer server.Close()
+
+	vc, err := NewVaultClient(logging.NewLogger(), config.VaultEnv{Address: server.URL, MaxRetries: 3, KVVersion: kvVersion1})
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+
+	_, err = vc.GetCredential(context.Background(), "secret", "app/db")
+	var rateLimitedErr *RateLimitedError
+	if !errors.As(err, &rateLimitedErr) {
+		t.Fatalf("GetCredential() error = %v, want RateLimitedError", err)
+	}
+	if rateLimitedErr.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %s, want 1s", rateLimitedErr.RetryAfter)
+	}
+	if !IsRetryableError(err) {
+		t.Errorf("IsRetryableError() = false for the rate limited error")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("api client sent %d requests, want the 429 left to the operation retry", got)
+	}
+
+	cred, err := vc.GetCredential(context.Background(), "secret", "app/db")
+	if err != nil || cred["password"] != "secret" {
+		t.Errorf("GetCredential() after the rate limit = %v, %v", cred, err)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "server error", err: &api.ResponseError{StatusCode: http.StatusInternalServerError}, want: true},
+		{name: "sealed", err: errors.WithMessage(&api.ResponseError{StatusCode: http.StatusServiceUnavailable}, "error in putting credentail"), want: true},
+		{name: "too many requests", err: &api.ResponseError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "rate limited", err: &RateLimitedError{URL: "/v1/secret/data/app", RetryAfter: time.Second}, want: true},
+		{name: "timeout", err: &TimeoutError{Operation: "write", Timeout: time.Second, Err: context.DeadlineExceeded}, want: true},
+		{name: "connection refused", err: &url.Error{Op: "Put", URL: "http://vault:8200", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}}, want: true},
+		{name: "network error", err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, want: true},
+		{name: "permission denied", err: &api.ResponseError{StatusCode: http.StatusForbidden}, want: false},
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "protected credential", err: errors.WithMessage(ErrProtectedSecret, "secret/app"), want: false},
+		{name: "credential not found", err: errors.WithMessage(ErrCredentialNotFound, "secret/app"), want: false},
+		{name: "check-and-set not supported", err: errors.New("check-and-set is not supported for kv version 1 mount secret"), want: false},
+		{name: "kv version not supported", err: errors.New("kv version 3 not supported"), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
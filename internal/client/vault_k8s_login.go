@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/api"
+	vaultauth "github.com/hashicorp/vault/api/auth/kubernetes"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/pkg/errors"
+)
+
+const AuthMethodKubernetes = "kubernetes"
+
+// NewVaultClientForKubernetesAuth returns a vault client logged in with the pod service account token
+// against the configured kubernetes auth role and mount. The token file is read on every login, so a
+// rotated projected token is picked up when the client logs in again before the token lease expires.
+func NewVaultClientForKubernetesAuth(log logging.Logger, conf config.VaultEnv) (*VaultClient, error) {
+	if conf.K8sAuthRole == "" {
+		return nil, errors.New("vault kubernetes auth role is required")
+	}
+
+	vc, err := NewVaultClient(log, conf)
+	if err != nil {
+		return nil, err
+	}
+
+	vc.auth = &vaultAuth{login: func(ctx context.Context) (*api.SecretAuth, error) {
+		k8sAuth, err := vaultauth.NewKubernetesAuth(
+			conf.K8sAuthRole,
+			vaultauth.WithMountPath(conf.K8sAuthMountPath),
+			vaultauth.WithServiceAccountTokenPath(conf.K8sAuthTokenPath),
+		)
+		if err != nil {
+			return nil, errors.WithMessage(err, "error in initializing Kubernetes auth method")
+		}
+
+		authInfo, err := vc.c.Auth().Login(ctx, k8sAuth)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "error in login with Kubernetes auth%s", vc.namespaceRef())
+		}
+		if authInfo == nil || authInfo.Auth == nil {
+			return nil, errors.New("no auth info was returned after login")
+		}
+		return authInfo.Auth, nil
+	}}
+
+	if err := vc.authenticate(context.Background()); err != nil {
+		return nil, err
+	}
+	return vc, nil
+}
@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	kvVersion1 = 1
+	kvVersion2 = 2
+)
+
+// ErrMountNotFound is returned when the credential mount does not exist and is not created.
+var ErrMountNotFound = errors.New("vault mount not found")
+
+// kvVersion returns the KV secrets engine version of the mount, the configured
+// version is used when set, else it is detected from the mount options and cached
+func (vc *VaultClient) kvVersion(ctx context.Context, mountPath string) (int, error) {
+	if vc.conf.KVVersion != 0 {
+		if vc.conf.KVVersion != kvVersion1 && vc.conf.KVVersion != kvVersion2 {
+			return 0, errors.Errorf("kv version %d not supported", vc.conf.KVVersion)
+		}
+		return vc.conf.KVVersion, nil
+	}
+
+	mountPath = strings.Trim(mountPath, "/")
+	vc.kvVersionsMutex.Lock()
+	defer vc.kvVersionsMutex.Unlock()
+	if version, ok := vc.kvVersions[mountPath]; ok {
+		return version, nil
+	}
+
+	version, err := vc.detectKVVersion(ctx, mountPath)
+	if err != nil {
+		return 0, err
+	}
+	vc.kvVersions[mountPath] = version
+	return version, nil
+}
+
+// EnsureMount verifies the secrets engine mount exists, a KV v2 mount is enabled at the path
+// when it is missing and create is set
+func (vc *VaultClient) EnsureMount(ctx context.Context, mountPath string, create bool) error {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return err
+	}
+	mounts, err := vc.c.Sys().ListMountsWithContext(ctx)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to list vault mounts%s", vc.namespaceRef())
+	}
+	mountPath = strings.Trim(mountPath, "/")
+	if _, found := mounts[mountPath+"/"]; found {
+		return nil
+	}
+	if !create {
+		return errors.WithMessagef(ErrMountNotFound, "no mount at %s, enable a kv secrets engine at %s or set auto create mount",
+			vc.secretPathRef(mountPath), mountPath)
+	}
+
+	err = vc.c.Sys().MountWithContext(ctx, mountPath, &api.MountInput{
+		Type:        "kv",
+		Description: "vault-cred credentials",
+		Options:     map[string]string{"version": "2"},
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to enable kv v2 mount %s", vc.secretPathRef(mountPath))
+	}
+	vc.log.Infof("enabled kv v2 mount %s", vc.secretPathRef(mountPath))
+	return nil
+}
+
+// detectKVVersion reads the mount options through the ui mounts endpoint, which is
+// readable with the permissions on the secret path, same as the vault cli does
+func (vc *VaultClient) detectKVVersion(ctx context.Context, mountPath string) (int, error) {
+	secret, err := vc.c.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+mountPath)
+	if err != nil {
+		return 0, errors.WithMessagef(err, "error in reading mount %s", vc.secretPathRef(mountPath))
+	}
+	if secret == nil || secret.Data == nil {
+		return 0, errors.Errorf("mount %s not found", vc.secretPathRef(mountPath))
+	}
+
+	options, _ := secret.Data["options"].(map[string]interface{})
+	if options == nil {
+		return kvVersion1, nil
+	}
+	switch fmt.Sprintf("%v", options["version"]) {
+	case "2":
+		return kvVersion2, nil
+	case "1", "", "<nil>":
+		return kvVersion1, nil
+	default:
+		return 0, errors.Errorf("mount %s has unsupported kv version %v", vc.secretPathRef(mountPath), options["version"])
+	}
+}
+
+func (vc *VaultClient) kvGet(ctx context.Context, mountPath, secretPath string) (*api.KVSecret, error) {
+	return vc.kvGetFrom(ctx, vc.c, mountPath, secretPath)
+}
+
+// kvGetFrom reads the secret through the api client, which may be the client of the read endpoint
+func (vc *VaultClient) kvGetFrom(ctx context.Context, c *api.Client, mountPath, secretPath string) (*api.KVSecret, error) {
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return nil, err
+	}
+	if version == kvVersion1 {
+		return c.KVv1(mountPath).Get(ctx, secretPath)
+	}
+	return c.KVv2(mountPath).Get(ctx, secretPath)
+}
+
+// kvPut writes the data as is for KV v1 and wrapped in data for KV v2, returning the KV v2 version
+// written or 0 for KV v1. KV v1 has no versions so the KV v2 options like check-and-set are not applied.
+func (vc *VaultClient) kvPut(ctx context.Context, mountPath, secretPath string, data map[string]interface{}, opts ...api.KVOption) (int, error) {
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return 0, err
+	}
+	if err := vc.waitWriteLimit(ctx); err != nil {
+		return 0, err
+	}
+	if version == kvVersion1 {
+		return 0, vc.c.KVv1(mountPath).Put(ctx, secretPath, data)
+	}
+	secret, err := vc.c.KVv2(mountPath).Put(ctx, secretPath, data, opts...)
+	if err != nil || secret == nil || secret.VersionMetadata == nil {
+		return 0, err
+	}
+	return secret.VersionMetadata.Version, nil
+}
+
+// ListCredentialPaths recursively lists the credential paths under the path prefix of the mount,
+// using LIST on the KV v2 metadata endpoint or on the KV v1 path
+func (vc *VaultClient) ListCredentialPaths(ctx context.Context, mountPath, pathPrefix string) ([]string, error) {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return nil, err
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return nil, err
+	}
+
+	listRoot := strings.Trim(mountPath, "/")
+	if version == kvVersion2 {
+		listRoot += "/metadata"
+	}
+
+	paths := []string{}
+	pending := []string{strings.Trim(pathPrefix, "/")}
+	for len(pending) != 0 {
+		dir := pending[0]
+		pending = pending[1:]
+
+		listPath := listRoot
+		if dir != "" {
+			listPath += "/" + dir
+		}
+		secret, err := vc.c.Logical().ListWithContext(ctx, listPath)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "error in listing credentials at %s", vc.secretPathRef(listPath))
+		}
+		if secret == nil || secret.Data == nil {
+			continue
+		}
+		keys, _ := secret.Data["keys"].([]interface{})
+		for _, key := range keys {
+			name := fmt.Sprintf("%v", key)
+			entry := strings.TrimPrefix(dir+"/"+name, "/")
+			if strings.HasSuffix(name, "/") {
+				pending = append(pending, strings.TrimSuffix(entry, "/"))
+				continue
+			}
+			paths = append(paths, entry)
+		}
+	}
+	return paths, nil
+}
+
+// GetCredentialMetadata returns the KV v2 custom metadata of the credential,
+// it fails with ErrMetadataNotSupported for KV v1 mounts
+func (vc *VaultClient) GetCredentialMetadata(ctx context.Context, mountPath, secretPath string) (map[string]string, error) {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return nil, err
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return nil, err
+	}
+	if version == kvVersion1 {
+		return nil, errors.WithMessagef(ErrMetadataNotSupported, "kv version 1 mount %s", vc.secretPathRef(mountPath))
+	}
+
+	metadata, err := vc.c.KVv2(mountPath).GetMetadata(ctx, secretPath)
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return nil, errors.WithMessagef(ErrCredentialNotFound, "no credential at %s", vc.secretPathRef(secretPath))
+		}
+		return nil, errors.WithMessagef(err, "error in reading credentail metadata at %s", vc.secretPathRef(secretPath))
+	}
+
+	customMetadata := map[string]string{}
+	for key, val := range metadata.CustomMetadata {
+		customMetadata[key] = fmt.Sprintf("%v", val)
+	}
+	return customMetadata, nil
+}
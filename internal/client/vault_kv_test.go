@@ -0,0 +1,177 @@
+package client
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+	"github.com/pkg/errors"
+)
+
+func TestKVVersion(t *testing.T) {
+	tests := []struct {
+		name          string
+		mountVersion  int
+		confVersion   int
+		mountPath     string
+		want          int
+		wantErr       bool
+		wantDetection bool
+	}{
+		{name: "detect v1", mountVersion: 1, mountPath: "secret", want: 1, wantDetection: true},
+		{name: "detect v2", mountVersion: 2, mountPath: "secret", want: 2, wantDetection: true},
+		{name: "detect trims slashes", mountVersion: 2, mountPath: "/secret/", want: 2, wantDetection: true},
+		{name: "configured v1 overrides v2 mount", mountVersion: 2, confVersion: 1, mountPath: "secret", want: 1},
+		{name: "configured v2 overrides v1 mount", mountVersion: 1, confVersion: 2, mountPath: "secret", want: 2},
+		{name: "configured version not supported", mountVersion: 2, confVersion: 3, mountPath: "secret", wantErr: true},
+		{name: "mount not found", mountVersion: 2, mountPath: "missing", wantErr: true, wantDetection: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": tt.mountVersion})
+			vc := newTestVaultClient(t, server, config.VaultEnv{KVVersion: tt.confVersion})
+
+			for i := 0; i < 2; i++ {
+				got, err := vc.kvVersion(context.Background(), tt.mountPath)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("kvVersion() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Fatalf("kvVersion() = %d, want %d", got, tt.want)
+				}
+			}
+
+			detections := server.RequestCount("GET", "sys/internal/ui/mounts/secret") +
+				server.RequestCount("GET", "sys/internal/ui/mounts/missing")
+			switch {
+			case !tt.wantDetection && detections != 0:
+				t.Errorf("mount detected %d times with configured version", detections)
+			case tt.wantDetection && !tt.wantErr && detections != 1:
+				t.Errorf("mount detected %d times, want 1 with the version cached", detections)
+			}
+		})
+	}
+}
+
+func TestKVPutGet(t *testing.T) {
+	tests := []struct {
+		name         string
+		mountVersion int
+		wantVersions []int
+	}{
+		{name: "v1 has no versions", mountVersion: 1, wantVersions: []int{0, 0}},
+		{name: "v2 returns the version written", mountVersion: 2, wantVersions: []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": tt.mountVersion})
+			vc := newTestVaultClient(t, server, config.VaultEnv{})
+			ctx := context.Background()
+
+			for i, value := range []string{"first", "second"} {
+				written, err := vc.kvPut(ctx, "secret", "app/db", map[string]interface{}{"password": value})
+				if err != nil {
+					t.Fatalf("kvPut() error = %v", err)
+				}
+				if written != tt.wantVersions[i] {
+					t.Errorf("kvPut() version = %d, want %d", written, tt.wantVersions[i])
+				}
+
+				secret, err := vc.kvGetFrom(ctx, vc.c, "secret", "app/db")
+				if err != nil {
+					t.Fatalf("kvGetFrom() error = %v", err)
+				}
+				if got := secret.Data["password"]; got != value {
+					t.Errorf("kvGetFrom() password = %v, want %s", got, value)
+				}
+			}
+
+			cred, version, err := vc.GetCredentialWithVersion(ctx, "secret", "app/db")
+			if err != nil {
+				t.Fatalf("GetCredentialWithVersion() error = %v", err)
+			}
+			if cred["password"] != "second" || version != tt.wantVersions[1] {
+				t.Errorf("GetCredentialWithVersion() = %v, %d", cred, version)
+			}
+		})
+	}
+}
+
+func TestListCredentialPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		pathPrefix string
+		want       []string
+	}{
+		{name: "whole mount", pathPrefix: "", want: []string{"app/db", "app/nested/api", "app/nested/deep/token", "other"}},
+		{name: "prefix", pathPrefix: "app", want: []string{"app/db", "app/nested/api", "app/nested/deep/token"}},
+		{name: "prefix with slashes", pathPrefix: "/app/nested/", want: []string{"app/nested/api", "app/nested/deep/token"}},
+		{name: "missing prefix", pathPrefix: "none", want: []string{}},
+	}
+	for _, mountVersion := range []int{kvVersion1, kvVersion2} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				server := vaulttest.NewServer(t, map[string]int{"secret": mountVersion})
+				for _, secretPath := range []string{"app/db", "app/nested/api", "app/nested/deep/token", "other"} {
+					server.Seed("secret", secretPath, map[string]interface{}{"key": "value"})
+				}
+				vc := newTestVaultClient(t, server, config.VaultEnv{})
+
+				got, err := vc.ListCredentialPaths(context.Background(), "secret", tt.pathPrefix)
+				if err != nil {
+					t.Fatalf("ListCredentialPaths() error = %v", err)
+				}
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("kv v%d ListCredentialPaths() = %v, want %v", mountVersion, got, tt.want)
+				}
+
+				listRoot := "secret/"
+				if mountVersion == kvVersion2 {
+					listRoot = "secret/metadata/"
+				}
+				if tt.pathPrefix == "" && server.RequestCount("LIST", listRoot+"app") != 1 {
+					t.Errorf("kv v%d did not list the sub directory at %sapp", mountVersion, listRoot)
+				}
+			})
+		}
+	}
+}
+
+func TestEnsureMount(t *testing.T) {
+	tests := []struct {
+		name         string
+		mountPath    string
+		create       bool
+		sealed       bool
+		wantErr      bool
+		wantNotFound bool
+	}{
+		{name: "existing mount", mountPath: "secret"},
+		{name: "existing mount with slashes", mountPath: "/secret/"},
+		{name: "missing mount", mountPath: "creds", wantErr: true, wantNotFound: true},
+		{name: "missing mount created", mountPath: "creds", create: true},
+		{name: "sealed vault", mountPath: "secret", sealed: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": kvVersion2})
+			vc := newTestVaultClient(t, server, config.VaultEnv{})
+			server.SetSealed(tt.sealed)
+
+			err := vc.EnsureMount(context.Background(), tt.mountPath, tt.create)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnsureMount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, ErrMountNotFound); got != tt.wantNotFound {
+				t.Errorf("EnsureMount() error = %v, mount not found %v, want %v", err, got, tt.wantNotFound)
+			}
+			if tt.create && server.RequestCount("POST", "sys/mounts/"+tt.mountPath) != 1 {
+				t.Errorf("mount %s not enabled", tt.mountPath)
+			}
+		})
+	}
+}
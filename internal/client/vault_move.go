@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// MoveCredential copies the credential with its custom metadata to the new path and then deletes
+// the old path. The copy never overwrites an existing credential and it is removed again when the
+// metadata copy fails, so on a failure the credential is still found at one of the paths.
+func (vc *VaultClient) MoveCredential(ctx context.Context, mountPath, oldPath, newPath string) (err error) {
+	if err = vc.ensureAuth(ctx); err != nil {
+		return
+	}
+	if oldPath == newPath {
+		return errors.Errorf("credential at %s can not be moved to the same path", vc.secretPathRef(oldPath))
+	}
+	cred, _, err := vc.readCredential(ctx, vc.c, mountPath, oldPath)
+	if err != nil {
+		return
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return
+	}
+
+	if version == kvVersion1 {
+		err = vc.checkCredentialExists(ctx, mountPath, newPath)
+		if err == nil {
+			return errors.Errorf("credential already exists at %s", vc.secretPathRef(newPath))
+		}
+		if !errors.Is(err, ErrCredentialNotFound) {
+			return
+		}
+		if err = vc.PutCredential(ctx, mountPath, newPath, cred); err != nil {
+			return
+		}
+	} else if err = vc.copyCredentialKV2(ctx, mountPath, oldPath, newPath, cred); err != nil {
+		return
+	}
+
+	if err = vc.DeleteCredential(ctx, mountPath, oldPath); err != nil {
+		return errors.WithMessagef(err, "credential copied to %s but not deleted at the old path", vc.secretPathRef(newPath))
+	}
+	return nil
+}
+
+// copyCredentialKV2 writes the credential to the new path only if no credential exists there,
+// and copies the custom metadata of the old path except the content hash of the write
+func (vc *VaultClient) copyCredentialKV2(ctx context.Context, mountPath, oldPath, newPath string, cred map[string]string) error {
+	metadata, err := vc.GetCredentialMetadata(ctx, mountPath, oldPath)
+	if err != nil {
+		return err
+	}
+	err = vc.PutCredentialCAS(ctx, mountPath, newPath, cred, 0)
+	if errors.Is(err, ErrCASMismatch) {
+		return errors.Errorf("credential already exists at %s", vc.secretPathRef(newPath))
+	}
+
+	delete(metadata, ContentHashMetadataKey)
+	delete(metadata, ContentHashVersionMetadataKey)
+	if err == nil && len(metadata) != 0 {
+		err = vc.PutCredentialMetadata(ctx, mountPath, newPath, metadata)
+	}
+	if err != nil {
+		if deleteErr := vc.DeleteCredential(ctx, mountPath, newPath); deleteErr != nil {
+			vc.log.Errorf("failed to remove the partial copy of the credential at %s, %v", vc.secretPathRef(newPath), deleteErr)
+		}
+		return errors.WithMessagef(err, "failed to copy credential to %s", vc.secretPathRef(newPath))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SetSignedCertificate sets the signed certificate of the PKI mount intermediate CA,
+// POST /<mount>/intermediate/set-signed. The certificate PEM may include the CA chain.
+func (vc *VaultClient) SetSignedCertificate(ctx context.Context, pkiMountPath, certificatePEM string) error {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return err
+	}
+	if err := vc.waitWriteLimit(ctx); err != nil {
+		return err
+	}
+	setSignedPath := fmt.Sprintf("%s/intermediate/set-signed", strings.Trim(pkiMountPath, "/"))
+	_, err := vc.c.Logical().WriteWithContext(ctx, setSignedPath, map[string]interface{}{
+		"certificate": certificatePEM,
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "error in setting signed certificate of pki mount %s", vc.secretPathRef(pkiMountPath))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// ErrProtectedSecret is returned when an overwrite of a protected credential is refused
+var ErrProtectedSecret = errors.New("credential is protected")
+
+// protectedMetadataKey marks a credential protected when its custom metadata value is true,
+// e.g. vault kv metadata put -custom-metadata=protected=true <mount>/<path>
+const protectedMetadataKey = "protected"
+
+// checkProtection fails with ErrProtectedSecret when the protection is respected and the existing
+// KV v2 credential is marked with the protected custom metadata or has a delete_version_after set
+// by others than the client owner. KV v1 credentials have no metadata, so they are never protected.
+func (vc *VaultClient) checkProtection(ctx context.Context, mountPath, secretPath string) error {
+	if !vc.conf.RespectProtection {
+		return nil
+	}
+	version, err := vc.kvVersion(ctx, mountPath)
+	if err != nil || version == kvVersion1 {
+		return err
+	}
+
+	metadata, err := vc.c.KVv2(mountPath).GetMetadata(ctx, secretPath)
+	if err != nil {
+		if errors.Is(err, api.ErrSecretNotFound) {
+			return nil
+		}
+		return errors.WithMessagef(err, "error in reading credentail metadata at %s", vc.secretPathRef(secretPath))
+	}
+
+	if fmt.Sprintf("%v", metadata.CustomMetadata[protectedMetadataKey]) == "true" {
+		return errors.WithMessagef(ErrProtectedSecret, "%s is marked %s", vc.secretPathRef(secretPath), protectedMetadataKey)
+	}
+	owned := vc.conf.CredentialOwner != "" &&
+		fmt.Sprintf("%v", metadata.CustomMetadata[OwnerMetadataKey]) == vc.conf.CredentialOwner
+	if metadata.DeleteVersionAfter > 0 && !owned {
+		return errors.WithMessagef(ErrProtectedSecret, "%s has delete_version_after %s",
+			vc.secretPathRef(secretPath), metadata.DeleteVersionAfter)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	writeLimitersMutex sync.Mutex
+	// writeLimiters are shared by the clients of a vault address, as a client is created per sync run
+	writeLimiters = map[string]*rate.Limiter{}
+)
+
+// writeLimiter returns the limiter of the vault writes to the client address,
+// nil when no write rate limit is configured
+func (vc *VaultClient) writeLimiter() *rate.Limiter {
+	if vc.conf.VaultWriteRateLimit <= 0 {
+		return nil
+	}
+	writeLimitersMutex.Lock()
+	defer writeLimitersMutex.Unlock()
+	limiter, found := writeLimiters[vc.c.Address()]
+	if !found {
+		burst := vc.conf.VaultWriteBurst
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(vc.conf.VaultWriteRateLimit), burst)
+		writeLimiters[vc.c.Address()] = limiter
+	}
+	return limiter
+}
+
+// waitWriteLimit blocks the vault write until the configured write rate allows it
+func (vc *VaultClient) waitWriteLimit(ctx context.Context) error {
+	limiter := vc.writeLimiter()
+	if limiter == nil {
+		return nil
+	}
+	reservation := limiter.Reserve()
+	delay := reservation.Delay()
+	if delay == 0 {
+		return nil
+	}
+	vc.log.Debugf("vault write throttled for %s by the write rate limit of %v per second", delay, limiter.Limit())
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/vault/api"
+)
+
+// readClient returns the client of the read endpoint, e.g. a performance standby, for the reads of the
+// change detection, falling back to the client of the primary when no read endpoint is configured.
+// The writes, and the reads of check-and-set writes, always go to the primary.
+func (vc *VaultClient) readClient() *api.Client {
+	if vc.conf.VaultReadAddress == "" {
+		return vc.c
+	}
+	vc.readerMutex.Lock()
+	defer vc.readerMutex.Unlock()
+	if vc.reader == nil {
+		reader, err := vc.c.Clone()
+		if err == nil {
+			err = reader.SetAddress(vc.conf.VaultReadAddress)
+		}
+		if err != nil {
+			vc.log.Warn(fmt.Sprintf("failed to init vault read endpoint client, reading from %s, %v", vc.Address(), err))
+			return vc.c
+		}
+		if vc.conf.VaultNamespace != "" {
+			reader.SetNamespace(vc.conf.VaultNamespace)
+		}
+		vc.reader = reader
+	}
+	// the token is renewed or replaced on the primary client
+	vc.reader.SetToken(vc.c.Token())
+	return vc.reader
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+	"github.com/pkg/errors"
+)
+
+// newTestVaultClient returns a client of the test vault server with the given config
+func newTestVaultClient(t *testing.T, server *vaulttest.Server, conf config.VaultEnv) *VaultClient {
+	conf.Address = server.URL()
+	conf.TransitMountPath = vaulttest.TransitMount
+	vc, err := NewVaultClient(logging.NewLogger(), conf)
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+	vc.c.SetToken(vaulttest.Token)
+	return vc
+}
+
+func TestDeleteCredential(t *testing.T) {
+	tests := []struct {
+		name         string
+		mountVersion int
+		soft         bool
+		seed         bool
+		wantErr      error
+		wantVersions bool
+	}{
+		{name: "soft delete keeps the older versions", mountVersion: kvVersion2, soft: true, seed: true, wantVersions: true},
+		{name: "soft delete missing", mountVersion: kvVersion2, soft: true, wantErr: ErrCredentialNotFound},
+		{name: "delete removes all versions", mountVersion: kvVersion2, seed: true},
+		{name: "delete missing", mountVersion: kvVersion2, wantErr: ErrCredentialNotFound},
+		{name: "delete kv v1", mountVersion: kvVersion1, seed: true},
+		{name: "delete missing kv v1", mountVersion: kvVersion1, wantErr: ErrCredentialNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": tt.mountVersion})
+			if tt.seed {
+				server.Seed("secret", "generic/payments/db", map[string]interface{}{"password": "first"})
+				server.Seed("secret", "generic/payments/db", map[string]interface{}{"password": "second"})
+			}
+			vc := newTestVaultClient(t, server, config.VaultEnv{})
+			ctx := context.Background()
+
+			var err error
+			if tt.soft {
+				err = vc.SoftDeleteCredential(ctx, "secret", "generic/payments/db")
+			} else {
+				err = vc.DeleteCredential(ctx, "secret", "generic/payments/db")
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("delete error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, err = vc.GetCredential(ctx, "secret", "generic/payments/db")
+			if !errors.Is(err, ErrCredentialNotFound) {
+				t.Errorf("GetCredential() after delete error = %v, want ErrCredentialNotFound", err)
+			}
+			if tt.mountVersion == kvVersion2 {
+				_, err = vc.GetCredentialMetadata(ctx, "secret", "generic/payments/db")
+				if (err == nil) != tt.wantVersions {
+					t.Errorf("GetCredentialMetadata() after delete error = %v, want versions kept %v", err, tt.wantVersions)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+)
+
+// tokenFileReloadInterval bounds how long a token read from the token file is used
+// before the file is read again, so a token rotated by an external agent is picked up
+const tokenFileReloadInterval = time.Minute
+
+// useTokenFile sets the client token from the token file, which is read again
+// on every login of the token renewal and when the reload interval elapses
+func (vc *VaultClient) useTokenFile(tokenFile string) error {
+	vc.auth = &vaultAuth{maxTokenAge: tokenFileReloadInterval, login: func(ctx context.Context) (*api.SecretAuth, error) {
+		token, err := configValueOrFile("", tokenFile)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to read vault token file %s", tokenFile)
+		}
+		if token == "" {
+			return nil, errors.Errorf("vault token file %s is empty", tokenFile)
+		}
+		return &api.SecretAuth{ClientToken: token}, nil
+	}}
+	return vc.authenticate(context.Background())
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// renewTTLFraction of the token ttl elapses before the token is renewed
+	renewTTLFraction = 2.0 / 3.0
+	renewRetryDelay  = 10 * time.Second
+)
+
+// tokenRenewal runs the background renewal of the client token until stopped
+type tokenRenewal struct {
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// startTokenRenewal starts a goroutine which renews the token lease before it expires,
+// and logs in again with the auth method when the renewal fails or the token is not renewable.
+func (vc *VaultClient) startTokenRenewal() {
+	ctx, cancel := context.WithCancel(context.Background())
+	vc.renewal = &tokenRenewal{cancel: cancel}
+	vc.renewal.wg.Add(1)
+	go func() {
+		defer vc.renewal.wg.Done()
+		vc.renewTokenLoop(ctx)
+	}()
+}
+
+// Close stops the background token renewal of the client and its mirrors
+func (vc *VaultClient) Close() {
+	for _, mirror := range vc.mirrors {
+		mirror.Close()
+	}
+	if vc.renewal == nil {
+		return
+	}
+	vc.renewal.cancel()
+	vc.renewal.wg.Wait()
+}
+
+func (vc *VaultClient) renewTokenLoop(ctx context.Context) {
+	for {
+		ttl, renewable, err := vc.lookupToken(ctx)
+		if err != nil {
+			vc.log.Errorf("vault token lookup failed, %s", err)
+			if !sleepContext(ctx, renewRetryDelay) {
+				return
+			}
+			continue
+		}
+		if ttl == 0 {
+			vc.log.Debug("vault token does not expire, token renewal stopped")
+			return
+		}
+
+		if !sleepContext(ctx, time.Duration(float64(ttl)*renewTTLFraction)) {
+			return
+		}
+
+		if renewable {
+			_, err = vc.c.Auth().Token().RenewSelfWithContext(ctx, 0)
+			if err == nil {
+				vc.log.Debug("vault token renewed")
+				continue
+			}
+			vc.log.Errorf("vault token renewal failed, %s", err)
+		}
+
+		if vc.auth == nil {
+			vc.log.Errorf("vault token can't be renewed and no auth method to login again, token renewal stopped")
+			return
+		}
+		if err := vc.authenticate(ctx); err != nil {
+			vc.log.Errorf("vault login failed, %s", err)
+			if !sleepContext(ctx, renewRetryDelay) {
+				return
+			}
+		}
+	}
+}
+
+func (vc *VaultClient) lookupToken(ctx context.Context) (time.Duration, bool, error) {
+	secret, err := vc.c.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return 0, false, errors.WithMessagef(err, "failed to lookup vault token%s", vc.namespaceRef())
+	}
+	ttl, err := secret.TokenTTL()
+	if err != nil {
+		return 0, false, err
+	}
+	renewable, err := secret.TokenIsRenewable()
+	if err != nil {
+		return 0, false, err
+	}
+	return ttl, renewable, nil
+}
+
+// sleepContext waits for the duration and reports false when the context is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// transitCiphertextPrefix marks the values encrypted with the transit engine
+const transitCiphertextPrefix = "vault:v"
+
+// TransitEncrypt encrypts the plaintext with the transit engine key and returns the ciphertext
+func (vc *VaultClient) TransitEncrypt(ctx context.Context, keyName, plaintext string) (string, error) {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return "", err
+	}
+	encryptPath := fmt.Sprintf("%s/encrypt/%s", strings.Trim(vc.conf.TransitMountPath, "/"), keyName)
+	secret, err := vc.c.Logical().WriteWithContext(ctx, encryptPath, map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte(plaintext)),
+	})
+	if err != nil {
+		return "", errors.WithMessagef(err, "error in transit encrypt with key %s", vc.secretPathRef(keyName))
+	}
+	if secret == nil || secret.Data == nil {
+		return "", errors.Errorf("no transit encrypt response for key %s", vc.secretPathRef(keyName))
+	}
+
+	ciphertext, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return "", errors.Errorf("transit encrypt response has no ciphertext for key %s", vc.secretPathRef(keyName))
+	}
+	return ciphertext, nil
+}
+
+// TransitDecrypt decrypts the ciphertext with the transit engine key and returns the plaintext
+func (vc *VaultClient) TransitDecrypt(ctx context.Context, keyName, ciphertext string) (string, error) {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return "", err
+	}
+	decryptPath := fmt.Sprintf("%s/decrypt/%s", strings.Trim(vc.conf.TransitMountPath, "/"), keyName)
+	secret, err := vc.c.Logical().WriteWithContext(ctx, decryptPath, map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return "", errors.WithMessagef(err, "error in transit decrypt with key %s", vc.secretPathRef(keyName))
+	}
+	if secret == nil || secret.Data == nil {
+		return "", errors.Errorf("no transit decrypt response for key %s", vc.secretPathRef(keyName))
+	}
+
+	encodedPlaintext, ok := secret.Data["plaintext"].(string)
+	if !ok {
+		return "", errors.Errorf("transit decrypt response has no plaintext for key %s", vc.secretPathRef(keyName))
+	}
+	plaintext, err := base64.StdEncoding.DecodeString(encodedPlaintext)
+	if err != nil {
+		return "", errors.WithMessage(err, "transit decrypt plaintext is not valid base64")
+	}
+	return string(plaintext), nil
+}
+
+// TransitRewrap rewraps the ciphertext with the latest version of the transit engine key,
+// without revealing the plaintext
+func (vc *VaultClient) TransitRewrap(ctx context.Context, keyName, ciphertext string) (string, error) {
+	if err := vc.ensureAuth(ctx); err != nil {
+		return "", err
+	}
+	rewrapPath := fmt.Sprintf("%s/rewrap/%s", strings.Trim(vc.conf.TransitMountPath, "/"), keyName)
+	secret, err := vc.c.Logical().WriteWithContext(ctx, rewrapPath, map[string]interface{}{
+		"ciphertext": ciphertext,
+	})
+	if err != nil {
+		return "", errors.WithMessagef(err, "error in transit rewrap with key %s", vc.secretPathRef(keyName))
+	}
+	if secret == nil || secret.Data == nil {
+		return "", errors.Errorf("no transit rewrap response for key %s", vc.secretPathRef(keyName))
+	}
+
+	rewrapped, ok := secret.Data["ciphertext"].(string)
+	if !ok {
+		return "", errors.Errorf("transit rewrap response has no ciphertext for key %s", vc.secretPathRef(keyName))
+	}
+	return rewrapped, nil
+}
+
+// RewrapCredential rewraps the transit encrypted fields of the credential with the latest version of
+// the transit key and writes the credential back when any field moved to a newer key version.
+// KV v2 credentials are written with check-and-set, so a concurrent update fails with ErrCASMismatch.
+// It reports whether the credential was written.
+func (vc *VaultClient) RewrapCredential(ctx context.Context, mountPath, secretPath, transitKey string) (bool, error) {
+	cred, version, err := vc.GetCredentialWithVersion(ctx, mountPath, secretPath)
+	if err != nil {
+		return false, err
+	}
+
+	rewrapped := false
+	for key, val := range cred {
+		if !strings.HasPrefix(val, transitCiphertextPrefix) {
+			continue
+		}
+		newVal, err := vc.TransitRewrap(ctx, transitKey, val)
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to rewrap field %s of %s", key, vc.secretPathRef(secretPath))
+		}
+		if ciphertextKeyVersion(newVal) != ciphertextKeyVersion(val) {
+			cred[key] = newVal
+			rewrapped = true
+		}
+	}
+	if !rewrapped {
+		return false, nil
+	}
+
+	kvVersion, err := vc.kvVersion(ctx, mountPath)
+	if err != nil {
+		return false, err
+	}
+	if kvVersion == kvVersion1 {
+		return true, vc.PutCredential(ctx, mountPath, secretPath, cred)
+	}
+	return true, vc.PutCredentialCAS(ctx, mountPath, secretPath, cred, version)
+}
+
+// ciphertextKeyVersion returns the key version of a transit ciphertext, vault:v<version>:<ciphertext>
+func ciphertextKeyVersion(ciphertext string) string {
+	parts := strings.SplitN(ciphertext, ":", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,39 @@
+package job
+
+import (
+	"context"
+	"strings"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	certBackendKV  = "kv"
+	certBackendPKI = "pki"
+)
+
+// storePKICertificate submits the certificate with its CA chain as the signed certificate of the
+// PKI mount, the key stays with the PKI mount that generated the signing request
+func (v *VaultCredSync) storePKICertificate(ctx context.Context, vc *client.VaultClient, task syncTask, certData CertificateData) error {
+	log := v.logger(ctx)
+	certificatePEM := strings.TrimSpace(certData.Cert) + "\n" + strings.TrimSpace(certData.CACert) + "\n"
+	if v.DryRun {
+		log.Infof("dry run, %s certificate would be set as signed certificate of pki mount %s", task.id(), v.conf.PKIMountPath)
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+		return nil
+	}
+
+	err := retryWithBackoff(ctx, log, v.conf.VaultWriteMaxAttempts, v.conf.VaultWriteMaxElapsedTime, func() error {
+		return v.vaultOp(ctx, "pki set signed", func(ctx context.Context) error {
+			return vc.SetSignedCertificate(ctx, v.conf.PKIMountPath, certificatePEM)
+		})
+	})
+	if err != nil {
+		return errors.WithMessagef(err, "failed to set %s certificate in pki mount", task.id())
+	}
+	recordOutcome(ctx, credentialTypeLabel(task.key), 1, 0, 0)
+	log.Infof("set sync cert for %s/%s as signed certificate of pki mount %s",
+		certData.EntityName, certData.CertIndentifier, v.conf.PKIMountPath)
+	return nil
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+
+	"github.com/pkg/errors"
+)
+
+// decodeCertData decodes the certificate material in place as per the certificate data encoding
+func decodeCertData(certData *CertificateData) error {
+	if certData.Encoding == "" || certData.Encoding == certEncodingPlain {
+		return nil
+	}
+	if certData.Encoding != certEncodingBase64 {
+		return errors.Errorf("certificate encoding %s not supported", certData.Encoding)
+	}
+
+	for name, field := range map[string]*string{"caCert": &certData.CACert, "cert": &certData.Cert, "key": &certData.Key} {
+		decoded, err := base64.StdEncoding.DecodeString(*field)
+		if err != nil {
+			return errors.WithMessagef(err, "%s is not valid base64", name)
+		}
+		*field = string(decoded)
+	}
+	return nil
+}
+
+// validateCertData verifies that the certificate is a valid x509 certificate,
+// the key matches the certificate public key and the certificate is signed by the CA.
+// It returns the parsed leaf certificate.
+func validateCertData(caCertPEM, certPEM, keyPEM string) (*x509.Certificate, error) {
+	caCerts, err := parseCertificates(caCertPEM)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid ca certificate")
+	}
+
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid certificate")
+	}
+	leafCert := certs[0]
+
+	if _, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM)); err != nil {
+		return nil, errors.WithMessage(err, "key does not match the certificate")
+	}
+
+	for _, caCert := range caCerts {
+		if err := leafCert.CheckSignatureFrom(caCert); err == nil {
+			return leafCert, nil
+		}
+	}
+	return nil, errors.Errorf("certificate %s is not signed by the ca certificate", leafCert.Subject)
+}
+
+// buildFullChain concatenates the certificate chain and the ca certificates, leaf first,
+// verifying each certificate is signed by the one following it
+func buildFullChain(caCertPEM, certPEM string) (string, error) {
+	certs, err := parseCertificates(certPEM)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid certificate")
+	}
+	caCerts, err := parseCertificates(caCertPEM)
+	if err != nil {
+		return "", errors.WithMessage(err, "invalid ca certificate")
+	}
+
+	for i := 0; i < len(certs)-1; i++ {
+		if err := certs[i].CheckSignatureFrom(certs[i+1]); err != nil {
+			return "", errors.Errorf("certificate %s is not signed by the next certificate %s, the leaf certificate must come first",
+				certs[i].Subject, certs[i+1].Subject)
+		}
+	}
+
+	// the ca certificate issuing the last certificate of the chain follows it
+	issuer := certs[len(certs)-1]
+	chain := append([]*x509.Certificate{}, certs...)
+	remaining := caCerts
+	for len(remaining) != 0 {
+		next := -1
+		for i, caCert := range remaining {
+			if issuer.CheckSignatureFrom(caCert) == nil {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			break
+		}
+		issuer = remaining[next]
+		chain = append(chain, issuer)
+		remaining = append(remaining[:next:next], remaining[next+1:]...)
+	}
+	if len(chain) == len(certs) {
+		return "", errors.Errorf("certificate %s is not signed by the ca certificate", issuer.Subject)
+	}
+
+	fullChain := []byte{}
+	for _, cert := range chain {
+		fullChain = append(fullChain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	return string(fullChain), nil
+}
+
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	certs := []*x509.Certificate{}
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, errors.Errorf("unexpected pem block type %s", block.Type)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no pem certificate block found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,113 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	backupFormatLevel = 1
+	// backupS2KCount is the largest OpenPGP passphrase hashing count, to slow down guessing the passphrase
+	backupS2KCount = 65011712
+)
+
+// BackupCredential is a credential in a backup archive with the mount and path it is restored to
+type BackupCredential struct {
+	Mount    string            `json:"mount"`
+	Path     string            `json:"path"`
+	Type     string            `json:"type"`
+	Data     map[string]string `json:"data"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+type credentialBackup struct {
+	Version     int                `json:"version"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	Credentials []BackupCredential `json:"credentials"`
+}
+
+// BackupCredentials writes the credentials managed by the sync, those owned by the configured owner
+// or without an owner, to a backup archive symmetrically encrypted with the passphrase as an OpenPGP
+// message, which gpg --decrypt also reads.
+// The transit encrypted fields are kept encrypted. It returns the number of credentials backed up.
+func BackupCredentials(ctx context.Context, log logging.Logger, w io.Writer, passphrase string) (int, error) {
+	if passphrase == "" {
+		return 0, errors.New("backup passphrase is required")
+	}
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return 0, err
+	}
+
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+
+	paths, err := v.credentialPaths(ctx, vc, "")
+	if err != nil {
+		return 0, err
+	}
+
+	backup := credentialBackup{Version: backupFormatLevel, CreatedAt: time.Now().UTC(), Credentials: []BackupCredential{}}
+	for _, credPath := range paths {
+		if owner, owned := v.credentialOwned(ctx, vc, credPath.vaultPath); !owned {
+			log.Debugf("credential %s owned by %s, not backed up", credPath, owner)
+			continue
+		}
+		cred, err := vc.GetCredential(ctx, credPath.mount, credPath.path)
+		if err != nil {
+			return 0, errors.WithMessagef(err, "failed to read credential %s", credPath)
+		}
+		metadata, err := vc.GetCredentialMetadata(ctx, credPath.mount, credPath.path)
+		if err != nil && !errors.Is(err, client.ErrMetadataNotSupported) {
+			return 0, errors.WithMessagef(err, "failed to read credential metadata %s", credPath)
+		}
+		// the content hash is written again with the restored credential
+		delete(metadata, client.ContentHashMetadataKey)
+		delete(metadata, client.ContentHashVersionMetadataKey)
+
+		backup.Credentials = append(backup.Credentials, BackupCredential{Mount: credPath.mount, Path: credPath.path,
+			Type: credPath.credentialType, Data: cred, Metadata: metadata})
+	}
+
+	data, err := json.Marshal(backup)
+	if err != nil {
+		return 0, errors.WithMessage(err, "failed to encode backup")
+	}
+	archive, err := encryptBackup(data, passphrase)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(archive); err != nil {
+		return 0, errors.WithMessage(err, "failed to write backup archive")
+	}
+	return len(backup.Credentials), nil
+}
+
+// encryptBackup encrypts the backup with aes-256 as an OpenPGP symmetrically encrypted message
+func encryptBackup(data []byte, passphrase string) ([]byte, error) {
+	var archive bytes.Buffer
+	w, err := openpgp.SymmetricallyEncrypt(&archive, []byte(passphrase), &openpgp.FileHints{IsBinary: true},
+		&packet.Config{DefaultCipher: packet.CipherAES256, S2KCount: backupS2KCount})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to encrypt backup")
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, errors.WithMessage(err, "failed to encrypt backup")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.WithMessage(err, "failed to encrypt backup")
+	}
+	return archive.Bytes(), nil
+}
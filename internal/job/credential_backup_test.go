@@ -0,0 +1,43 @@
+package job
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBackupEncryption(t *testing.T) {
+	data := []byte(`{"version":1,"credentials":[{"mount":"secret","path":"generic/payments/db","data":{"password":"s3cret"}}]}`)
+	archive, err := encryptBackup(data, "passphrase")
+	if err != nil {
+		t.Fatalf("encryptBackup() error = %v", err)
+	}
+	if bytes.Contains(archive, []byte("s3cret")) {
+		t.Fatalf("backup archive contains the plaintext")
+	}
+
+	tampered := append([]byte{}, archive...)
+	tampered[len(tampered)-5] ^= 0xff
+	tests := []struct {
+		name       string
+		archive    []byte
+		passphrase string
+		wantErr    bool
+	}{
+		{name: "round trip", archive: archive, passphrase: "passphrase"},
+		{name: "wrong passphrase", archive: archive, passphrase: "wrong", wantErr: true},
+		{name: "no passphrase", archive: archive, passphrase: "", wantErr: true},
+		{name: "tampered", archive: tampered, passphrase: "passphrase", wantErr: true},
+		{name: "not an archive", archive: data, passphrase: "passphrase", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decryptBackup(tt.archive, tt.passphrase)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decryptBackup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, data) {
+				t.Errorf("decryptBackup() = %s, want %s", got, data)
+			}
+		})
+	}
+}
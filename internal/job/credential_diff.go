@@ -0,0 +1,145 @@
+package job
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// DiffReport is the drift of the credentials in vault from the credentials of the sync sources
+type DiffReport struct {
+	// Missing are the credentials of the sync sources not in vault
+	Missing []CredentialDrift `json:"missing"`
+	// Differing are the credentials in vault with data differing from the sync sources
+	Differing []CredentialDrift `json:"differing"`
+	// Extra are the vault paths of the managed credentials produced by no key of the sync sources
+	Extra    []string      `json:"extra"`
+	InSync   int           `json:"inSync"`
+	Failures []SyncFailure `json:"failures,omitempty"`
+}
+
+// CredentialDrift identifies a secret key with the vault path of its credential, and the differing
+// credential keys without their values
+type CredentialDrift struct {
+	Identifier  string   `json:"identifier"`
+	Path        string   `json:"path"`
+	Differences []string `json:"differences,omitempty"`
+}
+
+type diffReportKey struct{}
+
+type diffCollector struct {
+	mutex  sync.Mutex
+	report DiffReport
+}
+
+func diffCollectorFrom(ctx context.Context) (*diffCollector, bool) {
+	d, ok := ctx.Value(diffReportKey{}).(*diffCollector)
+	return d, ok
+}
+
+// Diff compares the credentials of the sync sources with the credentials in vault, without any writes
+func (v *VaultCredSync) Diff(ctx context.Context) (DiffReport, error) {
+	d := &diffCollector{report: DiffReport{Missing: []CredentialDrift{}, Differing: []CredentialDrift{}, Extra: []string{}}}
+	ctx = context.WithValue(newRunContext(ctx), diffReportKey{}, d)
+
+	sources, err := v.credentialSources(ctx)
+	if err != nil {
+		return d.report, err
+	}
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		return d.report, err
+	}
+
+	var errs *multierror.Error
+	var merger *entityMerger
+	if v.conf.MergeByEntity {
+		ctx, merger = withEntityMerger(ctx)
+	}
+	ctx = v.withCredentialVariables(ctx)
+	producedPaths := map[vaultPath]bool{}
+	for _, source := range sources {
+		data, _, err := source.source.Fetch(ctx)
+		if err != nil {
+			errs = multierror.Append(errs, errors.WithMessagef(err, "failed to read sync source %s", source.id))
+			continue
+		}
+		delete(data, syncFrequencyKey)
+
+		task := syncTask{source: source.id, namespace: source.namespace, secretName: source.secretName,
+			labels: v.propagatedLabels(source.source)}
+		for key, secretValue := range data {
+			if !v.credentialTypeEnabled(key) {
+				continue
+			}
+			if selected, _ := v.keySelected(key); !selected {
+				continue
+			}
+			task.key, task.value = key, secretValue
+			secretPaths, err := v.storeCredential(ctx, vc, task)
+			if err != nil {
+				d.report.Failures = append(d.report.Failures,
+					SyncFailure{Identifier: task.id(), CredentialType: credentialTypeLabel(key), Error: err.Error()})
+				continue
+			}
+			for _, secretPath := range secretPaths {
+				producedPaths[secretPath] = true
+			}
+		}
+	}
+	if merger != nil {
+		if _, err := v.writeMergedCredentials(ctx, vc, merger); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	paths, err := v.credentialPaths(ctx, vc, "")
+	if err != nil {
+		return d.report, multierror.Append(errs, err)
+	}
+	for _, credPath := range paths {
+		if producedPaths[credPath.vaultPath] {
+			continue
+		}
+		if _, owned := v.credentialOwned(ctx, vc, credPath.vaultPath); owned {
+			d.report.Extra = append(d.report.Extra, credPath.String())
+		}
+	}
+
+	sort.Slice(d.report.Missing, func(i, j int) bool { return d.report.Missing[i].Path < d.report.Missing[j].Path })
+	sort.Slice(d.report.Differing, func(i, j int) bool { return d.report.Differing[i].Path < d.report.Differing[j].Path })
+	sort.Strings(d.report.Extra)
+	return d.report, errs.ErrorOrNil()
+}
+
+// recordDrift compares the credential with the credential in vault at the path in the diff report
+func (v *VaultCredSync) recordDrift(ctx context.Context, vc *client.VaultClient, d *diffCollector,
+	task syncTask, secretPath vaultPath, cred map[string]string) error {
+	var existingCred map[string]string
+	err := v.vaultOp(ctx, "read", func(ctx context.Context) (err error) {
+		existingCred, err = vc.GetCredential(ctx, secretPath.mount, secretPath.path)
+		return
+	})
+	if err != nil && !errors.Is(err, client.ErrCredentialNotFound) {
+		return errors.WithMessagef(err, "failed to read %s secret data from vault", task.id())
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	drift := CredentialDrift{Identifier: task.id(), Path: secretPath.String()}
+	if err != nil {
+		d.report.Missing = append(d.report.Missing, drift)
+		return nil
+	}
+	if drift.Differences = credentialDiff(cred, existingCred); len(drift.Differences) != 0 {
+		d.report.Differing = append(d.report.Differing, drift)
+		return nil
+	}
+	d.report.InSync++
+	return nil
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"strings"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// encryptCredentialFields encrypts the configured credential fields with the kv encryption transit key.
+// Transit ciphertext differs on every encryption, so the stored ciphertext is kept when it still
+// decrypts to the same plaintext, which keeps unchanged credentials from being rewritten.
+func (v *VaultCredSync) encryptCredentialFields(ctx context.Context, vc *client.VaultClient,
+	secretPath vaultPath, cred map[string]string) (map[string]string, error) {
+	if v.conf.KVEncryptionKeyName == "" || len(v.conf.KVEncryptedFields) == 0 {
+		return cred, nil
+	}
+
+	var existingCred map[string]string
+	_ = v.vaultOp(ctx, "read", func(ctx context.Context) (err error) {
+		existingCred, err = vc.GetCredential(ctx, secretPath.mount, secretPath.path)
+		return
+	})
+
+	encryptedCred := make(map[string]string, len(cred))
+	for key, val := range cred {
+		encryptedCred[key] = val
+	}
+	for _, field := range v.conf.KVEncryptedFields {
+		plaintext, found := cred[field]
+		if !found {
+			continue
+		}
+
+		if existingCiphertext := existingCred[field]; strings.HasPrefix(existingCiphertext, transitCiphertextPrefix) {
+			var existingPlaintext string
+			err := v.vaultOp(ctx, "transit decrypt", func(ctx context.Context) (err error) {
+				existingPlaintext, err = vc.TransitDecrypt(ctx, v.conf.KVEncryptionKeyName, existingCiphertext)
+				return
+			})
+			if err == nil && existingPlaintext == plaintext {
+				encryptedCred[field] = existingCiphertext
+				continue
+			}
+		}
+
+		err := v.vaultOp(ctx, "transit encrypt", func(ctx context.Context) (err error) {
+			encryptedCred[field], err = vc.TransitEncrypt(ctx, v.conf.KVEncryptionKeyName, plaintext)
+			return
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to encrypt field %s", field)
+		}
+	}
+	return encryptedCred, nil
+}
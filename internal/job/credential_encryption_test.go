@@ -0,0 +1,69 @@
+package job
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+)
+
+func TestEncryptCredentialFields(t *testing.T) {
+	server := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	conf := config.VaultEnv{KVEncryptionKeyName: "creds", KVEncryptedFields: []string{"password", "missing"}}
+	vc := newTestVaultClient(t, server, conf)
+	v := &VaultCredSync{log: logging.NewLogger(), conf: conf}
+	ctx := context.Background()
+	secretPath := vaultPath{mount: "secret", path: "generic/payments/db"}
+
+	encrypt := func(password string) map[string]string {
+		t.Helper()
+		encrypted, err := v.encryptCredentialFields(ctx, vc, secretPath, map[string]string{"user": "admin", "password": password})
+		if err != nil {
+			t.Fatalf("encryptCredentialFields() error = %v", err)
+		}
+		if encrypted["user"] != "admin" {
+			t.Errorf("field not configured for encryption changed to %q", encrypted["user"])
+		}
+		if _, found := encrypted["missing"]; found {
+			t.Errorf("encrypted field missing from the credential was added")
+		}
+		if !strings.HasPrefix(encrypted["password"], transitCiphertextPrefix) {
+			t.Fatalf("password %q not encrypted", encrypted["password"])
+		}
+		plaintext, err := vc.TransitDecrypt(ctx, conf.KVEncryptionKeyName, encrypted["password"])
+		if err != nil || plaintext != password {
+			t.Fatalf("TransitDecrypt() = %q, %v, want %q", plaintext, err, password)
+		}
+		return encrypted
+	}
+
+	first := encrypt("s3cret")
+	if err := vc.PutCredential(ctx, secretPath.mount, secretPath.path, first); err != nil {
+		t.Fatalf("PutCredential() error = %v", err)
+	}
+
+	unchanged := encrypt("s3cret")
+	if unchanged["password"] != first["password"] {
+		t.Errorf("ciphertext of the unchanged password = %q, want the stored %q kept", unchanged["password"], first["password"])
+	}
+	if got := server.RequestCount("POST", "transit/encrypt/creds") + server.RequestCount("PUT", "transit/encrypt/creds"); got != 1 {
+		t.Errorf("encrypted %d times, want the unchanged password not encrypted again", got)
+	}
+
+	changed := encrypt("rotated")
+	if changed["password"] == first["password"] {
+		t.Errorf("ciphertext of the changed password kept the stored ciphertext")
+	}
+}
+
+func TestEncryptCredentialFieldsNotConfigured(t *testing.T) {
+	v := &VaultCredSync{log: logging.NewLogger()}
+	cred := map[string]string{"password": "s3cret"}
+	encrypted, err := v.encryptCredentialFields(context.Background(), nil, vaultPath{}, cred)
+	if err != nil || encrypted["password"] != "s3cret" {
+		t.Errorf("encryptCredentialFields() = %v, %v, want the credential as is", encrypted, err)
+	}
+}
@@ -0,0 +1,57 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// The identifier json attributes were first named certIndetifier and credIndetifier, the
+// legacy names are still accepted when the certIdentifier or credIdentifier is not set.
+
+func (c *CertificateData) UnmarshalJSON(data []byte) error {
+	type certificateData CertificateData
+	aux := struct {
+		*certificateData
+		LegacyCertIdentifier string `json:"certIndetifier"`
+	}{certificateData: (*certificateData)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if c.CertIndentifier == "" {
+		c.CertIndentifier = aux.LegacyCertIdentifier
+	}
+	return nil
+}
+
+func (c *ServiceCredentail) UnmarshalJSON(data []byte) error {
+	type serviceCredential ServiceCredentail
+	aux := struct {
+		*serviceCredential
+		LegacyCredIdentifier string `json:"credIndetifier"`
+	}{serviceCredential: (*serviceCredential)(c)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if c.CredIndentifier == "" {
+		c.CredIndentifier = aux.LegacyCredIdentifier
+	}
+	return nil
+}
+
+// UnmarshalJSON keeps the numbers of the credential data as json numbers, so large integers are not rounded
+func (c *GenericCredential) UnmarshalJSON(data []byte) error {
+	type genericCredential GenericCredential
+	aux := struct {
+		*genericCredential
+		LegacyCredIdentifier string `json:"credIndetifier"`
+	}{genericCredential: (*genericCredential)(c)}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&aux); err != nil {
+		return err
+	}
+	if c.CredIndentifier == "" {
+		c.CredIndentifier = aux.LegacyCredIdentifier
+	}
+	return nil
+}
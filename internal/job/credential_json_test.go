@@ -0,0 +1,78 @@
+package job
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnmarshalJSONIdentifier(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{name: "identifier", fields: map[string]string{"current": "current"}, want: "current"},
+		{name: "legacy identifier", fields: map[string]string{"legacy": "legacy"}, want: "legacy"},
+		{name: "both identifiers", fields: map[string]string{"current": "current", "legacy": "legacy"}, want: "current"},
+		{name: "empty identifier falls back to legacy", fields: map[string]string{"current": "", "legacy": "legacy"}, want: "legacy"},
+		{name: "no identifier", fields: map[string]string{}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var certData CertificateData
+			if err := json.Unmarshal(identifierJSON(t, tt.fields, "certIdentifier", "certIndetifier"), &certData); err != nil {
+				t.Fatalf("certificate data error = %v", err)
+			}
+			if certData.CertIndentifier != tt.want {
+				t.Errorf("certificate identifier = %q, want %q", certData.CertIndentifier, tt.want)
+			}
+
+			var serviceCredData ServiceCredentail
+			if err := json.Unmarshal(identifierJSON(t, tt.fields, "credIdentifier", "credIndetifier"), &serviceCredData); err != nil {
+				t.Fatalf("service credential error = %v", err)
+			}
+			if serviceCredData.CredIndentifier != tt.want {
+				t.Errorf("service credential identifier = %q, want %q", serviceCredData.CredIndentifier, tt.want)
+			}
+
+			var genericCredData GenericCredential
+			if err := json.Unmarshal(identifierJSON(t, tt.fields, "credIdentifier", "credIndetifier"), &genericCredData); err != nil {
+				t.Fatalf("generic credential error = %v", err)
+			}
+			if genericCredData.CredIndentifier != tt.want {
+				t.Errorf("generic credential identifier = %q, want %q", genericCredData.CredIndentifier, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnmarshalJSONGenericCredentialNumbers(t *testing.T) {
+	var genericCredData GenericCredential
+	err := json.Unmarshal([]byte(`{"entityName":"payments","credIdentifier":"db","credential":{"id":9007199254740993}}`), &genericCredData)
+	if err != nil {
+		t.Fatalf("generic credential error = %v", err)
+	}
+	if genericCredData.EntityName != "payments" {
+		t.Errorf("entity name = %q, want payments", genericCredData.EntityName)
+	}
+	if got, _ := credentialValueString(genericCredData.Credential["id"]); got != "9007199254740993" {
+		t.Errorf("credential id = %s, want the integer kept as is", got)
+	}
+}
+
+// identifierJSON encodes the current and legacy identifier fields with their json attribute names
+func identifierJSON(t *testing.T, fields map[string]string, identifier, legacyIdentifier string) []byte {
+	data := map[string]string{}
+	for field, val := range fields {
+		if field == "current" {
+			data[identifier] = val
+			continue
+		}
+		data[legacyIdentifier] = val
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("failed to encode the identifiers: %v", err)
+	}
+	return encoded
+}
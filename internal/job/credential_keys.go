@@ -0,0 +1,60 @@
+package job
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/pkg/errors"
+)
+
+const (
+	// keyNormalizationNone stores the credential keys as is
+	keyNormalizationNone = "none"
+	// keyNormalizationNormalize lowercases the credential keys and replaces their spaces with underscores
+	keyNormalizationNormalize = "normalize"
+	// keyNormalizationStrict rejects the credential keys that are not normalized
+	keyNormalizationStrict = "strict"
+)
+
+func validKeyNormalization(normalization string) bool {
+	return normalization == keyNormalizationNone || normalization == keyNormalizationNormalize ||
+		normalization == keyNormalizationStrict
+}
+
+func normalizeKey(key string) string {
+	return strings.Join(strings.Fields(strings.ToLower(key)), "_")
+}
+
+// normalizeCredentialKeys applies the key normalization to the generic credential keys,
+// failing when two keys normalize to the same key or, in strict mode, on a key not normalized
+func normalizeCredentialKeys(log logging.Logger, cred map[string]string, normalization string) (map[string]string, error) {
+	if normalization == keyNormalizationNone {
+		return cred, nil
+	}
+
+	keys := make([]string, 0, len(cred))
+	for key := range cred {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	normalizedCred := make(map[string]string, len(cred))
+	for _, key := range keys {
+		normalizedKey := normalizeKey(key)
+		if normalizedKey == "" {
+			return nil, errors.Errorf("credential key %q is empty", key)
+		}
+		if normalizedKey != key {
+			if normalization == keyNormalizationStrict {
+				return nil, errors.Errorf("credential key %q is not normalized, expected %q", key, normalizedKey)
+			}
+			log.Infof("credential key %q normalized to %q", key, normalizedKey)
+		}
+		if _, found := normalizedCred[normalizedKey]; found {
+			return nil, errors.Errorf("credential key %q conflicts with another key normalized to %q", key, normalizedKey)
+		}
+		normalizedCred[normalizedKey] = cred[key]
+	}
+	return normalizedCred, nil
+}
@@ -0,0 +1,129 @@
+package job
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/api"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// Credential types of the listed credentials, derived from the vault path
+const (
+	CredentialTypeService = "service"
+	CredentialTypeCert    = "cert"
+	CredentialTypeGeneric = "generic"
+)
+
+// CredentialInfo describes a credential stored in vault
+type CredentialInfo struct {
+	Path     string
+	Type     string
+	Metadata map[string]string
+}
+
+// ListCredentials recursively lists the credentials under the credential mounts and the secret path prefix,
+// along with their custom metadata. The credentials are filtered by the credential type when set.
+func ListCredentials(ctx context.Context, log logging.Logger, credentialType string) ([]CredentialInfo, error) {
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return nil, err
+	}
+
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+
+	paths, err := v.credentialPaths(ctx, vc, credentialType)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := []CredentialInfo{}
+	for _, credPath := range paths {
+		metadata, err := vc.GetCredentialMetadata(ctx, credPath.mount, credPath.path)
+		if err != nil && !errors.Is(err, client.ErrMetadataNotSupported) {
+			return nil, err
+		}
+		credentials = append(credentials, CredentialInfo{
+			Path:     credPath.String(),
+			Type:     credPath.credentialType,
+			Metadata: metadata,
+		})
+	}
+	return credentials, nil
+}
+
+// typedVaultPath is a vault path of a credential with the credential type derived from the path
+type typedVaultPath struct {
+	vaultPath
+	credentialType string
+}
+
+// credentialPaths recursively lists the credential paths under the credential mounts and the
+// secret path prefix, filtered by the credential type when set
+func (v *VaultCredSync) credentialPaths(ctx context.Context, vc *client.VaultClient, credentialType string) ([]typedVaultPath, error) {
+	pathPrefix := strings.Trim(v.conf.SecretPathPrefix, "/")
+	credPaths := []typedVaultPath{}
+	for _, mountPath := range v.credentialMountPaths() {
+		paths, err := vc.ListCredentialPaths(ctx, mountPath, pathPrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, secretPath := range paths {
+			pathType := credentialTypeOfPath(strings.TrimPrefix(strings.TrimPrefix(secretPath, pathPrefix), "/"))
+			if credentialType != "" && pathType != credentialType {
+				continue
+			}
+			credPaths = append(credPaths, typedVaultPath{vaultPath: vaultPath{mount: mountPath, path: secretPath},
+				credentialType: pathType})
+		}
+	}
+	return credPaths, nil
+}
+
+// credentialMountPaths returns the default credential mount with the mounts configured per credential type
+// and the mounts of the key routes
+func (v *VaultCredSync) credentialMountPaths() []string {
+	mountPaths := []string{api.CredentialMountPath()}
+	configuredMountPaths := []string{}
+	for _, mountPath := range v.conf.CredentialMountPaths {
+		configuredMountPaths = append(configuredMountPaths, mountPath)
+	}
+	for _, route := range v.keyRoutes {
+		configuredMountPaths = append(configuredMountPaths, route.mountPath)
+	}
+	for _, mountPath := range configuredMountPaths {
+		found := false
+		for _, existingMountPath := range mountPaths {
+			if existingMountPath == mountPath {
+				found = true
+				break
+			}
+		}
+		if !found {
+			mountPaths = append(mountPaths, mountPath)
+		}
+	}
+	sort.Strings(mountPaths[1:])
+	return mountPaths
+}
+
+// credentialTypeOfPath derives the credential type from the first segment of the secret path
+func credentialTypeOfPath(secretPath string) string {
+	firstSegment, _, _ := strings.Cut(secretPath, "/")
+	switch firstSegment {
+	case strings.ToLower(serviceCredSecretKeyPrefix):
+		return CredentialTypeService
+	case strings.ToLower(certSecretKeyPrefix):
+		return CredentialTypeCert
+	default:
+		return CredentialTypeGeneric
+	}
+}
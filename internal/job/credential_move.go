@@ -0,0 +1,40 @@
+package job
+
+import (
+	"context"
+	"strings"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/api"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// MoveCredential moves the credential to the new path of the mount, the credential mount
+// when empty, e.g. after the entity of the credential was renamed. The sync secret keys
+// have to be renamed as well, or the next sync writes the credential to the old path again.
+func MoveCredential(ctx context.Context, log logging.Logger, mountPath, oldPath, newPath string) error {
+	oldPath, newPath = strings.Trim(oldPath, "/"), strings.Trim(newPath, "/")
+	if oldPath == "" || newPath == "" {
+		return errors.New("both the credential path and the new path are required")
+	}
+	if mountPath == "" {
+		mountPath = api.CredentialMountPath()
+	}
+
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return err
+	}
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+
+	if err := vc.MoveCredential(ctx, mountPath, oldPath, newPath); err != nil {
+		return err
+	}
+	log.Infof("moved credential %s/%s to %s/%s", mountPath, oldPath, mountPath, newPath)
+	return nil
+}
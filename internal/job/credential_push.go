@@ -0,0 +1,93 @@
+package job
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// pushCredentialSource is the audit metadata source of the credentials pushed directly
+const pushCredentialSource = "cli"
+
+// PushedCredential is the outcome of a pushed credential at a vault path
+type PushedCredential struct {
+	Path    string
+	Written bool
+	// SkipReason describes why the credential was not written, empty when it was
+	SkipReason string
+}
+
+type pushSkipsKey struct{}
+
+// pushSkips holds the reason of every vault path skipped by a push
+type pushSkips struct {
+	mutex   sync.Mutex
+	reasons map[vaultPath]string
+}
+
+// recordSkipReason records why the credential at the vault path was not written, for the push in
+// the context, if any
+func recordSkipReason(ctx context.Context, secretPath vaultPath, reason string) {
+	s, ok := ctx.Value(pushSkipsKey{}).(*pushSkips)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.reasons[secretPath] = reason
+}
+
+// PushCredential stores a single credential to vault without a kubernetes sync secret, using the
+// same validation and store logic as the sync. The secret key prefix selects the credential type
+// and the data is the sync secret value. It returns the outcome at every vault path of the
+// credential, several for a list of service credentials.
+func PushCredential(ctx context.Context, log logging.Logger, secretKey, secretData string) ([]PushedCredential, error) {
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return nil, err
+	}
+	if v.conf.MergeByEntity {
+		// a single credential written to the entity path would drop the merged keys of the other credentials
+		return nil, errors.New("pushing a credential is not supported in the merge by entity mode")
+	}
+
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+	return v.pushCredential(ctx, vc, secretKey, secretData)
+}
+
+func (v *VaultCredSync) pushCredential(ctx context.Context, vc *client.VaultClient,
+	secretKey, secretData string) ([]PushedCredential, error) {
+	skips := &pushSkips{reasons: map[vaultPath]string{}}
+	ctx = context.WithValue(ctx, pushSkipsKey{}, skips)
+
+	task := syncTask{key: secretKey, value: secretData, source: pushCredentialSource}
+	secretPaths, err := v.storeCredential(ctx, vc, task)
+	if err != nil {
+		return nil, err
+	}
+	if len(secretPaths) == 0 {
+		return nil, errors.Errorf("credential type of %s not supported", secretKey)
+	}
+	pushed := make([]PushedCredential, 0, len(secretPaths))
+	for _, secretPath := range secretPaths {
+		reason, skipped := skips.reasons[secretPath]
+		pushed = append(pushed, PushedCredential{Path: secretPath.String(), Written: !skipped, SkipReason: reason})
+	}
+	sort.Slice(pushed, func(i, j int) bool { return pushed[i].Path < pushed[j].Path })
+	return pushed, nil
+}
+
+// Secret key prefixes of the credential types, for PushCredential
+const (
+	ServiceCredentialKeyPrefix = serviceCredSecretKeyPrefix
+	CertificateKeyPrefix       = certSecretKeyPrefix
+	GenericCredentialKeyPrefix = genericSecretKeyPrefix
+)
@@ -0,0 +1,46 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+)
+
+func TestPushCredentialReportsSkips(t *testing.T) {
+	server := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	v := newTestCredSync(t, server, config.VaultEnv{}, nil)
+	ctx := context.Background()
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		t.Fatalf("vaultClient() error = %v", err)
+	}
+
+	push := func(password string) PushedCredential {
+		t.Helper()
+		pushed, err := v.pushCredential(ctx, vc, genericSecretKeyPrefix+"-cli", genericCredentialValue("payments", password))
+		if err != nil {
+			t.Fatalf("pushCredential() error = %v", err)
+		}
+		if len(pushed) != 1 || pushed[0].Path != "secret/database/payments/db" {
+			t.Fatalf("pushCredential() = %+v, want the single path secret/database/payments/db", pushed)
+		}
+		return pushed[0]
+	}
+
+	if p := push("p1"); !p.Written || p.SkipReason != "" {
+		t.Errorf("first push = %+v, want written", p)
+	}
+	if p := push("p1"); p.Written || p.SkipReason != "unchanged" {
+		t.Errorf("unchanged push = %+v, want skipped as unchanged", p)
+	}
+
+	v.DryRun = true
+	if p := push("p2"); p.Written || p.SkipReason != "dry run" {
+		t.Errorf("dry run push = %+v, want skipped as dry run", p)
+	}
+	if data, _ := server.Latest("secret", "database/payments/db"); data["password"] != "p1" {
+		t.Errorf("password after the dry run = %v, want p1", data["password"])
+	}
+}
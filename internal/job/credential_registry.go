@@ -0,0 +1,72 @@
+package job
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// CredentialValidator validates the credential data of a generic credential type
+type CredentialValidator func(cred map[string]string) error
+
+// RequiredKeysValidator rejects credentials missing any of the required keys
+func RequiredKeysValidator(requiredKeys ...string) CredentialValidator {
+	return func(cred map[string]string) error {
+		missingKeys := []string{}
+		for _, key := range requiredKeys {
+			if len(cred[key]) == 0 {
+				missingKeys = append(missingKeys, key)
+			}
+		}
+		if len(missingKeys) != 0 {
+			sort.Strings(missingKeys)
+			return errors.Errorf("required keys %v are missing", missingKeys)
+		}
+		return nil
+	}
+}
+
+// credentialTypeRegistry holds the validators of the generic credential types
+type credentialTypeRegistry struct {
+	mutex      sync.RWMutex
+	validators map[string]CredentialValidator
+}
+
+// newCredentialTypeRegistry seeds the registry with the required keys per credential type,
+// the keys of a type are separated by ';'
+func newCredentialTypeRegistry(requiredKeys map[string]string) *credentialTypeRegistry {
+	r := &credentialTypeRegistry{validators: map[string]CredentialValidator{}}
+	for credentialType, keys := range requiredKeys {
+		keyList := []string{}
+		for _, key := range strings.Split(keys, ";") {
+			if key = strings.TrimSpace(key); key != "" {
+				keyList = append(keyList, key)
+			}
+		}
+		r.register(credentialType, RequiredKeysValidator(keyList...))
+	}
+	return r
+}
+
+func (r *credentialTypeRegistry) register(credentialType string, validator CredentialValidator) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.validators[credentialType] = validator
+}
+
+// validate runs the validator of the credential type, unknown types are
+// rejected in strict mode and pass through otherwise
+func (r *credentialTypeRegistry) validate(credentialType string, cred map[string]string, strict bool) error {
+	r.mutex.RLock()
+	validator, found := r.validators[credentialType]
+	r.mutex.RUnlock()
+	if !found {
+		if strict {
+			return errors.Errorf("credential type %s is not registered", credentialType)
+		}
+		return nil
+	}
+	return validator(cred)
+}
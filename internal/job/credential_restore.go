@@ -0,0 +1,125 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/go-multierror"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// RestoreResult counts the credentials of a backup archive by restore outcome
+type RestoreResult struct {
+	Restored int
+	Skipped  int
+	Failed   int
+}
+
+// RestoreCredentials writes the credentials of the backup archive back to their vault mounts and paths
+// with their custom metadata. The credentials already in vault are skipped unless overwrite is set,
+// and in dry run mode the restore is only logged. A failed credential does not stop the restore of the others.
+func RestoreCredentials(ctx context.Context, log logging.Logger, r io.Reader, passphrase string,
+	overwrite, dryRun bool) (RestoreResult, error) {
+	result := RestoreResult{}
+	archive, err := io.ReadAll(r)
+	if err != nil {
+		return result, errors.WithMessage(err, "failed to read backup archive")
+	}
+	data, err := decryptBackup(archive, passphrase)
+	if err != nil {
+		return result, err
+	}
+	var backup credentialBackup
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return result, errors.WithMessage(err, "failed to decode backup")
+	}
+	if backup.Version != backupFormatLevel {
+		return result, errors.Errorf("backup version %d not supported", backup.Version)
+	}
+
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return result, err
+	}
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return result, errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+
+	var errs *multierror.Error
+	for _, credential := range backup.Credentials {
+		credPath := vaultPath{mount: credential.Mount, path: credential.Path}
+		if !overwrite {
+			_, err := vc.GetCredential(ctx, credPath.mount, credPath.path)
+			if err == nil {
+				log.Infof("credential %s exists, not restored", credPath)
+				result.Skipped++
+				continue
+			}
+			if !errors.Is(err, client.ErrCredentialNotFound) {
+				errs = multierror.Append(errs, errors.WithMessagef(err, "failed to read credential %s", credPath))
+				result.Failed++
+				continue
+			}
+		}
+		if dryRun {
+			log.Infof("dry run, credential %s would be restored", credPath)
+			result.Restored++
+			continue
+		}
+
+		if err := restoreCredential(ctx, vc, credPath, credential); err != nil {
+			errs = multierror.Append(errs, err)
+			result.Failed++
+			continue
+		}
+		log.Infof("restored credential %s", credPath)
+		result.Restored++
+	}
+	return result, errs.ErrorOrNil()
+}
+
+func restoreCredential(ctx context.Context, vc *client.VaultClient, credPath vaultPath, credential BackupCredential) error {
+	if err := vc.PutCredential(ctx, credPath.mount, credPath.path, credential.Data); err != nil {
+		return errors.WithMessagef(err, "failed to restore credential %s", credPath)
+	}
+	if len(credential.Metadata) == 0 {
+		return nil
+	}
+	err := vc.PutCredentialMetadata(ctx, credPath.mount, credPath.path, credential.Metadata)
+	if err != nil && !errors.Is(err, client.ErrMetadataNotSupported) {
+		return errors.WithMessagef(err, "failed to restore credential metadata %s", credPath)
+	}
+	return nil
+}
+
+// decryptBackup decrypts the backup archive written by encryptBackup
+func decryptBackup(archive []byte, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("backup passphrase is required")
+	}
+	prompted := false
+	md, err := openpgp.ReadMessage(bytes.NewReader(archive), nil, func(_ []openpgp.Key, symmetric bool) ([]byte, error) {
+		// the prompt is called again while the passphrase does not decrypt the archive
+		if !symmetric || prompted {
+			return nil, errors.New("wrong passphrase")
+		}
+		prompted = true
+		return []byte(passphrase), nil
+	}, nil)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt backup archive, wrong passphrase or not a backup archive")
+	}
+	// the integrity of the message is verified once the body is read to the end
+	data, err := io.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to decrypt backup archive, corrupted archive")
+	}
+	return data, nil
+}
@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// RewrapCredentials rewraps the transit encrypted fields of every credential of the credential type,
+// all types when empty, with the latest version of the transit key, which defaults to the kv
+// encryption key. It returns the paths of the credentials written, a failed credential does not
+// stop the rewrap of the others.
+func RewrapCredentials(ctx context.Context, log logging.Logger, credentialType, transitKey string) ([]string, error) {
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return nil, err
+	}
+	if transitKey == "" {
+		transitKey = v.conf.KVEncryptionKeyName
+	}
+	if transitKey == "" {
+		return nil, errors.New("transit key is required to rewrap credentials")
+	}
+
+	vc, err := client.NewVaultClientForAuthMethod(log, v.conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init vault client")
+	}
+	defer vc.Close()
+
+	paths, err := v.credentialPaths(ctx, vc, credentialType)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs *multierror.Error
+	rewrappedPaths := []string{}
+	for _, credPath := range paths {
+		written, err := vc.RewrapCredential(ctx, credPath.mount, credPath.path, transitKey)
+		if err != nil {
+			errs = multierror.Append(errs, errors.WithMessagef(err, "failed to rewrap %s", credPath))
+			continue
+		}
+		if written {
+			rewrappedPaths = append(rewrappedPaths, credPath.String())
+		}
+	}
+	return rewrappedPaths, errs.ErrorOrNil()
+}
@@ -0,0 +1,224 @@
+package job
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// CredentialSource provides the secret keys to sync along with the time they were last updated
+type CredentialSource interface {
+	Fetch(ctx context.Context) (map[string]string, time.Time, error)
+}
+
+// namedSource is a credential source of a sync run with the identifiers of its keys
+type namedSource struct {
+	// id identifies the source across runs for the change detection
+	id string
+	// namespace and secretName qualify the key ids and vault paths when syncing multiple sources
+	namespace  string
+	secretName string
+	source     CredentialSource
+	// object is the kubernetes object read for the source, nil for the other sources
+	object *sourceObject
+}
+
+// ownsTaskID reports whether the key id is of a key of the source, the keys of a source listing
+// the sync secrets of a namespace are the keys of every sync secret of the namespace
+func (s namedSource) ownsTaskID(id string) bool {
+	return strings.HasPrefix(id, syncTask{namespace: s.namespace, secretName: s.secretName}.id())
+}
+
+func sourcesOwnTaskID(sources []namedSource, id string) bool {
+	for _, source := range sources {
+		if source.ownsTaskID(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourcesCoverSourceID reports whether the source id is of one of the sources, or of a sync
+// secret listed by one of them
+func sourcesCoverSourceID(sources []namedSource, id string) bool {
+	for _, source := range sources {
+		if id == source.id || strings.HasPrefix(id, source.id+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceObject identifies the kubernetes object of a sync source, which the sync events are recorded on
+type sourceObject struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// SetCredentialSources replaces the kubernetes sync secrets with the sources, by source name
+func (v *VaultCredSync) SetCredentialSources(sources map[string]CredentialSource) {
+	v.sources = sources
+}
+
+// credentialSources returns the sources of the sync run
+func (v *VaultCredSync) credentialSources(ctx context.Context) ([]namedSource, error) {
+	if len(v.sources) != 0 {
+		sources := make([]namedSource, 0, len(v.sources))
+		for name, source := range v.sources {
+			namedSource := namedSource{id: name, source: source}
+			if len(v.sources) > 1 {
+				namedSource.secretName = name
+			}
+			sources = append(sources, namedSource)
+		}
+		sort.Slice(sources, func(i, j int) bool { return sources[i].id < sources[j].id })
+		return sources, nil
+	}
+	return v.k8sCredentialSources(ctx)
+}
+
+// k8sCredentialSources returns a source per sync secret of every sync namespace, the secrets
+// matching the sync secret selector and name prefix are listed when configured
+func (v *VaultCredSync) k8sCredentialSources(ctx context.Context) ([]namedSource, error) {
+	var k8s *client.K8SClient
+	err := v.k8sOp(ctx, func() (err error) {
+		k8s, err = client.NewK8SClient(v.logger(ctx))
+		return
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init k8s client")
+	}
+
+	namespaces, multiNamespace, err := v.syncSecretNamespaces(ctx, k8s)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newSyncSourceReader(k8s, v.conf.SyncSourceKind)
+	if err != nil {
+		return nil, err
+	}
+	reader = &retryingReader{reader: reader, v: v}
+
+	objectKind := "Secret"
+	if v.conf.SyncSourceKind == syncSourceKindConfigMap {
+		objectKind = "ConfigMap"
+	}
+
+	sources := []namedSource{}
+	for _, namespace := range namespaces {
+		source := namedSource{}
+		if multiNamespace {
+			source.namespace = namespace
+		}
+
+		if !v.multiSecret() {
+			source.id = namespace + "/" + v.conf.VaultCredSyncSecretName
+			source.source = &k8sCredentialSource{reader: reader, name: v.conf.VaultCredSyncSecretName, namespace: namespace}
+			source.object = &sourceObject{kind: objectKind, namespace: namespace, name: v.conf.VaultCredSyncSecretName}
+			sources = append(sources, source)
+			continue
+		}
+
+		secrets, err := reader.list(ctx, namespace, v.conf.SyncSecretSelector, v.conf.SyncSecretPrefix)
+		if err != nil {
+			source.id = namespace
+			source.source = failedCredentialSource{err: err}
+			source.object = nil
+			sources = append(sources, source)
+			continue
+		}
+		for _, secret := range secrets {
+			source.id = secret.Namespace + "/" + secret.Name
+			source.secretName = secret.Name
+			source.source = fetchedCredentialSource{data: secret.Data, updatedTime: secret.LastUpdatedTime,
+				objectLabels: secret.Labels, objectAnnotations: secret.Annotations}
+			source.object = &sourceObject{kind: objectKind, namespace: secret.Namespace, name: secret.Name}
+			sources = append(sources, source)
+		}
+	}
+	return sources, nil
+}
+
+// labeledCredentialSource is a credential source with the labels of its kubernetes object,
+// the labels are read by the fetch
+type labeledCredentialSource interface {
+	labels() map[string]string
+}
+
+// k8sCredentialSource reads the keys of a kubernetes sync secret or configmap
+type k8sCredentialSource struct {
+	reader       syncSourceReader
+	name         string
+	namespace    string
+	objectLabels map[string]string
+	// objectAnnotations are read by the fetch
+	objectAnnotations map[string]string
+}
+
+func (s *k8sCredentialSource) Fetch(ctx context.Context) (map[string]string, time.Time, error) {
+	secret, err := s.reader.get(ctx, s.name, s.namespace)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	s.objectLabels, s.objectAnnotations = secret.Labels, secret.Annotations
+	return secret.Data, secret.LastUpdatedTime, nil
+}
+
+func (s *k8sCredentialSource) labels() map[string]string {
+	return s.objectLabels
+}
+
+func (s *k8sCredentialSource) annotations() map[string]string {
+	return s.objectAnnotations
+}
+
+// fetchedCredentialSource holds the keys already read while listing the sync secrets
+type fetchedCredentialSource struct {
+	data         map[string]string
+	updatedTime  time.Time
+	objectLabels map[string]string
+	// objectAnnotations of the listed sync secret
+	objectAnnotations map[string]string
+}
+
+func (s fetchedCredentialSource) Fetch(ctx context.Context) (map[string]string, time.Time, error) {
+	return s.data, s.updatedTime, nil
+}
+
+func (s fetchedCredentialSource) labels() map[string]string {
+	return s.objectLabels
+}
+
+func (s fetchedCredentialSource) annotations() map[string]string {
+	return s.objectAnnotations
+}
+
+// propagatedLabels returns the labels of the source allowed to be propagated to the credential metadata
+func (v *VaultCredSync) propagatedLabels(source CredentialSource) map[string]string {
+	labeledSource, ok := source.(labeledCredentialSource)
+	if !ok || len(v.conf.PropagateLabels) == 0 {
+		return nil
+	}
+	sourceLabels := labeledSource.labels()
+	labels := map[string]string{}
+	for _, key := range v.conf.PropagateLabels {
+		if val, found := sourceLabels[key]; found {
+			labels[key] = val
+		}
+	}
+	return labels
+}
+
+// failedCredentialSource reports the failure to list the sync secrets of a namespace
+type failedCredentialSource struct {
+	err error
+}
+
+func (s failedCredentialSource) Fetch(ctx context.Context) (map[string]string, time.Time, error) {
+	return nil, time.Time{}, s.err
+}
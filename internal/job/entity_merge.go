@@ -0,0 +1,138 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// the credentials of an entity are merged to the <entity>/<entityName>/credentials path
+const (
+	entityCredentialType       = "entity"
+	entityCredentialIdentifier = "credentials"
+)
+
+type entityMergerKey struct{}
+
+// entityMerger collects the credentials of the sync run by their entity path, to be written
+// merged once every secret key is stored
+type entityMerger struct {
+	mutex    sync.Mutex
+	entities map[vaultPath]*entityCredentials
+}
+
+type entityCredentials struct {
+	tasks []syncTask
+	creds []map[string]string
+}
+
+func withEntityMerger(ctx context.Context) (context.Context, *entityMerger) {
+	m := &entityMerger{entities: map[vaultPath]*entityCredentials{}}
+	return context.WithValue(ctx, entityMergerKey{}, m), m
+}
+
+func entityMergerFrom(ctx context.Context) (*entityMerger, bool) {
+	m, ok := ctx.Value(entityMergerKey{}).(*entityMerger)
+	return m, ok && m != nil
+}
+
+func (m *entityMerger) add(task syncTask, secretPath vaultPath, cred map[string]string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entity, found := m.entities[secretPath]
+	if !found {
+		entity = &entityCredentials{}
+		m.entities[secretPath] = entity
+	}
+	entity.tasks = append(entity.tasks, task)
+	entity.creds = append(entity.creds, cred)
+}
+
+// writeMergedCredentials writes the merged credential of every entity and returns the errors
+// of the failed writes by the secret key ids merged, along with the aggregated errors. The keys are merged in the order of the secret
+// key ids, on a conflict the value of the first secret key wins. An entity with a key of a paused
+// or not due source is kept unchanged.
+func (v *VaultCredSync) writeMergedCredentials(ctx context.Context, vc *client.VaultClient, m *entityMerger) (map[string]error, error) {
+	log := v.logger(ctx)
+	// the merged credentials are written without the merger, which would collect them again
+	ctx = context.WithValue(ctx, entityMergerKey{}, (*entityMerger)(nil))
+	var errs *multierror.Error
+	failedTasks := map[string]error{}
+	for secretPath, entity := range m.entities {
+		order := make([]int, len(entity.tasks))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(i, j int) bool { return entity.tasks[order[i]].id() < entity.tasks[order[j]].id() })
+
+		frozen := false
+		merged, mergedFrom := map[string]string{}, map[string]string{}
+		for _, i := range order {
+			task := entity.tasks[i]
+			frozen = frozen || task.frozen
+			for key, val := range entity.creds[i] {
+				existing, found := merged[key]
+				if !found {
+					merged[key], mergedFrom[key] = val, task.id()
+					continue
+				}
+				if existing != val {
+					log.Warn(fmt.Sprintf("credential key %s of %s conflicts with %s at %s, keeping the value of %s",
+						key, task.id(), mergedFrom[key], secretPath, mergedFrom[key]))
+				}
+			}
+		}
+		task := entity.tasks[order[0]]
+		if frozen {
+			log.Debugf("merged credential at %s kept unchanged, a merged source is paused or not due", secretPath)
+			recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+			continue
+		}
+
+		if _, err := v.putCredential(ctx, vc, task, secretPath, merged); err != nil {
+			errs = multierror.Append(errs, err)
+			for _, mergedTask := range entity.tasks {
+				failedTasks[entryTaskID(mergedTask.id())] = err
+			}
+		}
+	}
+	return failedTasks, errs.ErrorOrNil()
+}
+
+// syncMergedCredentials writes the merged credentials once every secret key of the run is stored.
+// The merged credential a failed key was merged into on the previous run would miss its keys, so
+// it is not written, the merged credentials of the other entities are.
+func (v *VaultCredSync) syncMergedCredentials(ctx context.Context, vc *client.VaultClient, m *entityMerger,
+	syncTasks []syncTask, failedTaskIDs map[string]bool, failedSources map[string][]error, errs *multierror.Error) *multierror.Error {
+	v.stateMutex.Lock()
+	previousSyncedPaths := v.syncedPaths
+	v.stateMutex.Unlock()
+	for id, secretPath := range previousSyncedPaths {
+		entity, found := m.entities[secretPath]
+		if !found || !failedTaskIDs[entryTaskID(id)] {
+			continue
+		}
+		err := errors.Errorf("merged credential at %s not written as secret key %s failed to sync", secretPath, entryTaskID(id))
+		errs = multierror.Append(errs, err)
+		for _, task := range entity.tasks {
+			failedSources[task.source] = append(failedSources[task.source], err)
+		}
+		delete(m.entities, secretPath)
+	}
+
+	failedTasks, err := v.writeMergedCredentials(ctx, vc, m)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	for _, task := range syncTasks {
+		if err, failed := failedTasks[task.id()]; failed {
+			failedSources[task.source] = append(failedSources[task.source], err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,62 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FileCredentialSource reads the secret keys from the files of a directory, the file name is
+// the secret key so its prefix determines the credential type and the file content is the value.
+// Hidden files, like the ..data links of mounted volumes, and sub directories are ignored.
+type FileCredentialSource struct {
+	dir string
+}
+
+func NewFileCredentialSource(dir string) *FileCredentialSource {
+	return &FileCredentialSource{dir: dir}
+}
+
+// Fetch returns the file contents by file name, the updated time is the latest modification
+// time of the directory and its files so any change is detected
+func (s *FileCredentialSource) Fetch(ctx context.Context) (map[string]string, time.Time, error) {
+	dirInfo, err := os.Stat(s.dir)
+	if err != nil {
+		return nil, time.Time{}, errors.WithMessagef(err, "failed to read credential directory %s", s.dir)
+	}
+	updatedTime := dirInfo.ModTime()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, time.Time{}, errors.WithMessagef(err, "failed to read credential directory %s", s.dir)
+	}
+
+	data := map[string]string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		filePath := filepath.Join(s.dir, entry.Name())
+		fileInfo, err := os.Stat(filePath)
+		if err != nil {
+			return nil, time.Time{}, errors.WithMessagef(err, "failed to read credential file %s", filePath)
+		}
+		if fileInfo.IsDir() {
+			continue
+		}
+
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, time.Time{}, errors.WithMessagef(err, "failed to read credential file %s", filePath)
+		}
+		data[entry.Name()] = string(content)
+		if fileInfo.ModTime().After(updatedTime) {
+			updatedTime = fileInfo.ModTime()
+		}
+	}
+	return data, updatedTime, nil
+}
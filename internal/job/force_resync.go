@@ -0,0 +1,28 @@
+package job
+
+import "context"
+
+type forceResyncKey struct{}
+
+// WithForceResync returns a context which makes the sync run write every credential,
+// ignoring the source last updated time and the per credential change detection
+func WithForceResync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceResyncKey{}, true)
+}
+
+func (v *VaultCredSync) forceResync(ctx context.Context) bool {
+	force, _ := ctx.Value(forceResyncKey{}).(bool)
+	return force || v.ForceResync
+}
+
+// Reset clears the last updated time of the sync sources and their sync frequency schedule, so the
+// next run syncs every source with the normal change detection. A run in progress does not record
+// its state over the reset. The synced paths are kept so the orphan credentials are still pruned.
+func (v *VaultCredSync) Reset() {
+	v.stateMutex.Lock()
+	defer v.stateMutex.Unlock()
+	v.lastUpdatedTimes = nil
+	v.sourceSyncTimes = nil
+	v.resetGeneration++
+	v.log.Infof("vault credential sync state reset, the next run is a full sync")
+}
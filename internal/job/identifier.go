@@ -0,0 +1,51 @@
+package job
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+)
+
+// generateIdentifier derives a deterministic credential identifier from the entity name and
+// credential data, so an unchanged credential synced again lands at the same path
+func (v *VaultCredSync) generateIdentifier(entityName string, cred map[string]string) string {
+	keys := make([]string, 0, len(cred))
+	for key := range cred {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	hash.Write([]byte(entityName))
+	for _, key := range keys {
+		hash.Write([]byte{0})
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write([]byte(cred[key]))
+	}
+	seed := hash.Sum(nil)
+
+	// the charset is indexed by character, so a multi byte character is never split
+	charset := []rune(v.conf.IdentifierCharset)
+	if len(charset) == 0 {
+		charset = []rune(defaultIdentifierCharset)
+	}
+	length := v.conf.IdentifierLength
+	if length <= 0 {
+		length = defaultIdentifierLength
+	}
+
+	// extend the digest by hashing it with a counter until there are enough bytes for the length
+	identifier := make([]rune, length)
+	for i := range identifier {
+		if i > 0 && i%(len(seed)/2) == 0 {
+			var counter [8]byte
+			binary.BigEndian.PutUint64(counter[:], uint64(i))
+			next := sha256.Sum256(append(seed, counter[:]...))
+			seed = next[:]
+		}
+		n := binary.BigEndian.Uint16(seed[(i%(len(seed)/2))*2:])
+		identifier[i] = charset[int(n)%len(charset)]
+	}
+	return string(identifier)
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/intelops/vault-cred/config"
+)
+
+func TestGenerateIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		charset string
+		length  int
+		want    string
+	}{
+		{name: "default charset", length: 40, want: defaultIdentifierCharset},
+		{name: "ascii charset", charset: "ab", length: 16, want: "ab"},
+		{name: "multi byte charset", charset: "äöüß€", length: 40, want: "äöüß€"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &VaultCredSync{conf: config.VaultEnv{IdentifierCharset: tt.charset, IdentifierLength: tt.length}}
+			cred := map[string]string{"user": "payments", "password": "s3cret"}
+
+			identifier := v.generateIdentifier("payments", cred)
+			if !utf8.ValidString(identifier) {
+				t.Fatalf("generateIdentifier() = %q is not valid utf-8", identifier)
+			}
+			if got := utf8.RuneCountInString(identifier); got != tt.length {
+				t.Errorf("generateIdentifier() has %d characters, want %d", got, tt.length)
+			}
+			for _, c := range identifier {
+				if !strings.ContainsRune(tt.want, c) {
+					t.Errorf("generateIdentifier() = %q has character %q outside the charset", identifier, c)
+				}
+			}
+			if again := v.generateIdentifier("payments", cred); again != identifier {
+				t.Errorf("generateIdentifier() = %q then %q for the same credential", identifier, again)
+			}
+		})
+	}
+}
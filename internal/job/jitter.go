@@ -0,0 +1,38 @@
+package job
+
+import (
+	"hash/fnv"
+	"os"
+	"time"
+)
+
+// hostJitter returns an offset within the window that is stable for the host name, so every
+// replica keeps its own offset across restarts while the replicas are spread over the window.
+func hostJitter(window time.Duration) time.Duration {
+	if window <= 0 {
+		return 0
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return 0
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(hostname))
+	return time.Duration(hash.Sum64() % uint64(window))
+}
+
+// waitJitter delays the scheduled run by the jitter offset, it returns false
+// when the job is shut down while waiting
+func (v *VaultCredSync) waitJitter() bool {
+	if v.jitter <= 0 {
+		return true
+	}
+	timer := time.NewTimer(v.jitter)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-v.shutdown.draining:
+		return false
+	}
+}
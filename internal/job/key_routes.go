@@ -0,0 +1,52 @@
+package job
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// keyRoute overrides the vault mount and path prefix of the credentials of the secret keys
+// matching its pattern, e.g. SERVICE-CRED-db-* routed to the db mount
+type keyRoute struct {
+	pattern    string
+	mountPath  string
+	pathPrefix string
+}
+
+// parseKeyRoutes parses the routes configured as pattern:mount or pattern:mount:path-prefix,
+// ordered from the most specific pattern so that the longest matching pattern wins
+func parseKeyRoutes(routes map[string]string) ([]keyRoute, error) {
+	keyRoutes := make([]keyRoute, 0, len(routes))
+	for pattern, target := range routes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.WithMessagef(err, "invalid key route pattern %s", pattern)
+		}
+		mountPath, pathPrefix, _ := strings.Cut(target, ":")
+		mountPath = strings.Trim(mountPath, "/")
+		if mountPath == "" {
+			return nil, errors.Errorf("key route %s has no mount", pattern)
+		}
+		keyRoutes = append(keyRoutes, keyRoute{pattern: pattern, mountPath: mountPath,
+			pathPrefix: strings.Trim(pathPrefix, "/")})
+	}
+	sort.Slice(keyRoutes, func(i, j int) bool {
+		if len(keyRoutes[i].pattern) != len(keyRoutes[j].pattern) {
+			return len(keyRoutes[i].pattern) > len(keyRoutes[j].pattern)
+		}
+		return keyRoutes[i].pattern < keyRoutes[j].pattern
+	})
+	return keyRoutes, nil
+}
+
+// keyRoute returns the route of the secret key, if any route pattern matches the key
+func (v *VaultCredSync) keyRoute(key string) (keyRoute, bool) {
+	for _, route := range v.keyRoutes {
+		if matched, _ := path.Match(route.pattern, key); matched {
+			return route, true
+		}
+	}
+	return keyRoute{}, false
+}
@@ -0,0 +1,88 @@
+package job
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// nestedEncodingFlatten stores the nested credential values under dot delimited keys
+	nestedEncodingFlatten = "flatten"
+	// nestedEncodingJSON stores every nested credential value json encoded under its top level key
+	nestedEncodingJSON = "json"
+)
+
+func validNestedEncoding(encoding string) bool {
+	return encoding == nestedEncodingFlatten || encoding == nestedEncodingJSON
+}
+
+// flattenCredential converts the generic credential data into the flat string map stored in vault
+func flattenCredential(data map[string]interface{}, encoding string) (map[string]string, error) {
+	cred := map[string]string{}
+	for key, val := range data {
+		nested, ok := val.(map[string]interface{})
+		if ok && encoding == nestedEncodingFlatten {
+			if err := flattenNested(cred, key, nested); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		strVal, err := credentialValueString(val)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "invalid credential value for %s", key)
+		}
+		if err := putFlattened(cred, key, strVal); err != nil {
+			return nil, err
+		}
+	}
+	return cred, nil
+}
+
+func flattenNested(cred map[string]string, prefix string, data map[string]interface{}) error {
+	for key, val := range data {
+		flatKey := prefix + "." + key
+		if nested, ok := val.(map[string]interface{}); ok {
+			if err := flattenNested(cred, flatKey, nested); err != nil {
+				return err
+			}
+			continue
+		}
+
+		strVal, err := credentialValueString(val)
+		if err != nil {
+			return errors.WithMessagef(err, "invalid credential value for %s", flatKey)
+		}
+		if err := putFlattened(cred, flatKey, strVal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func putFlattened(cred map[string]string, key, val string) error {
+	if _, ok := cred[key]; ok {
+		return errors.Errorf("credential key %s is defined more than once after flattening", key)
+	}
+	cred[key] = val
+	return nil
+}
+
+// credentialValueString keeps the string values as is and json encodes the other values
+func credentialValueString(val interface{}) (string, error) {
+	switch v := val.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case nil:
+		return "", nil
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,122 @@
+package job
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const nestedCredentialJSON = `{"credentialType":"generic","credential":{
+	"user":"admin",
+	"db":{"host":"db.local","port":5432,"tls":{"enabled":true,"ca":"-----BEGIN CERTIFICATE-----"}}}}`
+
+func TestFlattenCredentialNested(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		want     map[string]string
+	}{
+		{
+			name:     "flatten",
+			encoding: nestedEncodingFlatten,
+			want: map[string]string{
+				"user":           "admin",
+				"db.host":        "db.local",
+				"db.port":        "5432",
+				"db.tls.enabled": "true",
+				"db.tls.ca":      "-----BEGIN CERTIFICATE-----",
+			},
+		},
+		{
+			name:     "json",
+			encoding: nestedEncodingJSON,
+			want: map[string]string{
+				"user": "admin",
+				"db":   `{"host":"db.local","port":5432,"tls":{"ca":"-----BEGIN CERTIFICATE-----","enabled":true}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var genericCredData GenericCredential
+			if err := json.Unmarshal([]byte(nestedCredentialJSON), &genericCredData); err != nil {
+				t.Fatalf("failed to parse the credential: %v", err)
+			}
+
+			cred, err := flattenCredential(genericCredData.Credential, tt.encoding)
+			if err != nil {
+				t.Fatalf("flattenCredential() error = %v", err)
+			}
+			if !reflect.DeepEqual(cred, tt.want) {
+				t.Fatalf("flattenCredential() = %v, want %v", cred, tt.want)
+			}
+
+			if got := nestCredential(t, cred, tt.encoding); !reflect.DeepEqual(got, stringLeaves(genericCredData.Credential)) {
+				t.Errorf("round trip = %v, want %v", got, stringLeaves(genericCredData.Credential))
+			}
+		})
+	}
+}
+
+func TestFlattenCredentialDuplicateKey(t *testing.T) {
+	data := map[string]interface{}{
+		"db.host": "db.local",
+		"db":      map[string]interface{}{"host": "db.remote"},
+	}
+	if _, err := flattenCredential(data, nestedEncodingFlatten); err == nil {
+		t.Errorf("flattenCredential() of a key defined twice after flattening did not fail")
+	}
+	if _, err := flattenCredential(data, nestedEncodingJSON); err != nil {
+		t.Errorf("flattenCredential() json error = %v", err)
+	}
+}
+
+// nestCredential rebuilds the nested credential data from the values stored in vault,
+// with the leaf values as strings
+func nestCredential(t *testing.T, cred map[string]string, encoding string) map[string]interface{} {
+	data := map[string]interface{}{}
+	for key, val := range cred {
+		if encoding == nestedEncodingJSON {
+			if strings.HasPrefix(val, "{") {
+				var nested map[string]interface{}
+				decoder := json.NewDecoder(bytes.NewReader([]byte(val)))
+				decoder.UseNumber()
+				if err := decoder.Decode(&nested); err != nil {
+					t.Fatalf("value of %s is not json: %v", key, err)
+				}
+				data[key] = stringLeaves(nested)
+				continue
+			}
+			data[key] = val
+			continue
+		}
+
+		parts := strings.Split(key, ".")
+		parent := data
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent[part].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{}
+				parent[part] = child
+			}
+			parent = child
+		}
+		parent[parts[len(parts)-1]] = val
+	}
+	return data
+}
+
+// stringLeaves converts the leaf values of the nested data to the strings stored in vault
+func stringLeaves(data map[string]interface{}) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for key, val := range data {
+		if nested, ok := val.(map[string]interface{}); ok {
+			converted[key] = stringLeaves(nested)
+			continue
+		}
+		converted[key], _ = credentialValueString(val)
+	}
+	return converted
+}
@@ -0,0 +1,40 @@
+package job
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ParseError is returned when the secret data of a key is not valid credential json
+type ParseError struct {
+	SecretKey      string
+	CredentialType string
+	// Offset is the byte offset of the json error in the secret data, -1 when not known
+	Offset int64
+	Err    error
+}
+
+func newParseError(secretKey, credentialType string, err error) *ParseError {
+	parseErr := &ParseError{SecretKey: secretKey, CredentialType: credentialType, Offset: -1, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		parseErr.Offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		parseErr.Offset = typeErr.Offset
+	}
+	return parseErr
+}
+
+func (e *ParseError) Error() string {
+	if e.Offset < 0 {
+		return fmt.Sprintf("failed to parse %s %s secret data, %v", e.SecretKey, e.CredentialType, e.Err)
+	}
+	return fmt.Sprintf("failed to parse %s %s secret data at offset %d, %v", e.SecretKey, e.CredentialType, e.Offset, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"time"
+	"unicode/utf8"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	serviceCredentialPreviousPasswordKey = "previous_password"
+	serviceCredentialRotatedAtKey        = "rotated_at"
+)
+
+// rotatedServicePassword returns the password to store for a service credential with rotation enabled,
+// along with the previous password and the rotation time. The stored password is kept until it is older
+// than the rotation interval, then a new random password is generated and the old one is kept as previous.
+func (v *VaultCredSync) rotatedServicePassword(ctx context.Context, vc *client.VaultClient,
+	secretPath vaultPath, rotationInterval string) (password, previousPassword, rotatedAt string, err error) {
+	interval, err := time.ParseDuration(rotationInterval)
+	if err != nil || interval <= 0 {
+		return "", "", "", errors.Errorf("invalid password rotation interval %q", rotationInterval)
+	}
+
+	var existingCred map[string]string
+	err = v.vaultOp(ctx, "read", func(ctx context.Context) (err error) {
+		existingCred, err = vc.GetCredential(ctx, secretPath.mount, secretPath.path)
+		return
+	})
+	if err != nil && !errors.Is(err, client.ErrCredentialNotFound) {
+		return "", "", "", errors.WithMessage(err, "failed to read stored credential")
+	}
+
+	if existingCred != nil {
+		lastRotatedAt, err := time.Parse(time.RFC3339, existingCred[serviceCredentialRotatedAtKey])
+		if err == nil && time.Since(lastRotatedAt) < interval && existingCred[serviceCredentialPasswordKey] != "" {
+			return existingCred[serviceCredentialPasswordKey], existingCred[serviceCredentialPreviousPasswordKey],
+				existingCred[serviceCredentialRotatedAtKey], nil
+		}
+	}
+
+	password, err = generatePassword(v.conf.PasswordLength, v.conf.PasswordCharset)
+	if err != nil {
+		return "", "", "", errors.WithMessage(err, "failed to generate password")
+	}
+	return password, existingCred[serviceCredentialPasswordKey], time.Now().UTC().Format(time.RFC3339), nil
+}
+
+func generatePassword(length int, charset string) (string, error) {
+	if length <= 0 {
+		return "", errors.Errorf("invalid password length %d", length)
+	}
+	if len(charset) == 0 {
+		return "", errors.New("password charset is empty")
+	}
+	if !utf8.ValidString(charset) {
+		return "", errors.New("password charset is not valid utf-8")
+	}
+
+	// the length and the charset are in characters, so a multi byte character is never split
+	chars := []rune(charset)
+	password := make([]rune, length)
+	max := big.NewInt(int64(len(chars)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = chars[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestGeneratePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		charset string
+		wantErr bool
+	}{
+		{name: "ascii charset", length: 32, charset: "abcdefghijklmnopqrstuvwxyz0123456789"},
+		{name: "multi byte charset", length: 64, charset: "äöüß€😀"},
+		{name: "empty charset", length: 32, charset: "", wantErr: true},
+		{name: "invalid utf-8 charset", length: 32, charset: "ab\xff", wantErr: true},
+		{name: "invalid length", length: 0, charset: "ab", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			password, err := generatePassword(tt.length, tt.charset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generatePassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !utf8.ValidString(password) {
+				t.Fatalf("generatePassword() = %q is not valid utf-8", password)
+			}
+			if got := utf8.RuneCountInString(password); got != tt.length {
+				t.Errorf("generatePassword() has %d characters, want %d", got, tt.length)
+			}
+			for _, c := range password {
+				if !strings.ContainsRune(tt.charset, c) {
+					t.Errorf("generatePassword() = %q has character %q outside the charset", password, c)
+				}
+			}
+		})
+	}
+}
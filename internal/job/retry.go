@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	retryInitialInterval = 500 * time.Millisecond
+	retryMaxInterval     = 10 * time.Second
+)
+
+// retryWithBackoff calls the operation until it succeeds, fails with a non retryable error,
+// or the attempts or elapsed time are exhausted. Retries wait with exponential backoff and jitter,
+// or the time requested by vault when it rejects a request as rate limited.
+func retryWithBackoff(ctx context.Context, log logging.Logger, maxAttempts int, maxElapsedTime time.Duration,
+	operation func() error) error {
+	return retryWithBackoffIf(ctx, log, maxAttempts, maxElapsedTime, client.IsRetryableError, operation)
+}
+
+// retryWithBackoffIf is retryWithBackoff with the errors to retry reported by retryable
+func retryWithBackoffIf(ctx context.Context, log logging.Logger, maxAttempts int, maxElapsedTime time.Duration,
+	retryable func(error) bool, operation func() error) error {
+	jitter := rand.New(rand.NewSource(time.Now().UnixNano()))
+	startTime := time.Now()
+	interval := retryInitialInterval
+	for attempt := 1; ; attempt++ {
+		err := operation()
+		if err == nil || !retryable(err) || attempt >= maxAttempts {
+			return err
+		}
+
+		wait := interval/2 + time.Duration(jitter.Int63n(int64(interval)))
+		var rateLimitedErr *client.RateLimitedError
+		if errors.As(err, &rateLimitedErr) {
+			wait = rateLimitedErr.RetryAfter
+		}
+		if time.Since(startTime)+wait > maxElapsedTime {
+			return err
+		}
+
+		log.Debugf("attempt %d failed, retrying in %s, %v", attempt, wait, err)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "retry aborted after %d attempts, %v", attempt, err)
+		case <-time.After(wait):
+		}
+
+		interval *= 2
+		if interval > retryMaxInterval {
+			interval = retryMaxInterval
+		}
+	}
+}
+
+// k8sOp retries the kubernetes operation while the api server is unreachable, it is not
+// retried when the object is not found or the request is rejected
+func (v *VaultCredSync) k8sOp(ctx context.Context, operation func() error) error {
+	return retryWithBackoffIf(ctx, v.logger(ctx), v.conf.K8sMaxAttempts, v.conf.K8sMaxElapsedTime,
+		client.IsK8sRetryableError, operation)
+}
+
+// vaultOp runs the vault operation with the configured operation timeout, an operation
+// exceeding the timeout fails with a client.TimeoutError so that it can be retried
+func (v *VaultCredSync) vaultOp(ctx context.Context, operation string, op func(ctx context.Context) error) error {
+	if v.conf.VaultOpTimeout <= 0 {
+		return op(ctx)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, v.conf.VaultOpTimeout)
+	defer cancel()
+	err := op(opCtx)
+	if err != nil && errors.Is(opCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		return &client.TimeoutError{Operation: operation, Timeout: v.conf.VaultOpTimeout, Err: err}
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+package job
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/client"
+)
+
+func TestRetryWithBackoffWaitsRetryAfter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"password":"secret"}}`))
+	}))
+	defer server.Close()
+
+	log := logging.NewLogger()
+	vc, err := client.NewVaultClientForVaultToken(log, config.VaultEnv{Address: server.URL, VaultToken: "test-token", KVVersion: 1})
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+
+	var cred map[string]string
+	startTime := time.Now()
+	err = retryWithBackoff(context.Background(), log, 3, time.Minute, func() (err error) {
+		cred, err = vc.GetCredential(context.Background(), "secret", "app/db")
+		return
+	})
+	elapsed := time.Since(startTime)
+	if err != nil {
+		t.Fatalf("retryWithBackoff() error = %v", err)
+	}
+	if cred["password"] != "secret" {
+		t.Errorf("credential = %v after the retry", cred)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("sent %d requests, want the 429 and one retry", got)
+	}
+	// the backoff without Retry-After waits at most 750ms for the first retry
+	if elapsed < time.Second {
+		t.Errorf("retried after %s, want the 1s Retry-After waited", elapsed)
+	}
+}
+
+func TestRetryWithBackoffRetryAfterExceedsElapsedTime(t *testing.T) {
+	attempts := 0
+	err := retryWithBackoff(context.Background(), logging.NewLogger(), 3, 500*time.Millisecond, func() error {
+		attempts++
+		return &client.RateLimitedError{URL: "/v1/secret/data/app", RetryAfter: time.Second}
+	})
+	if err == nil || attempts != 1 {
+		t.Errorf("retryWithBackoff() = %v after %d attempts, want the Retry-After beyond the elapsed time not waited", err, attempts)
+	}
+}
@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"runtime"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/sirupsen/logrus"
+)
+
+type runLoggerKey struct{}
+
+// runLogger is a logging.Logger that adds the sync run id to every log line,
+// so all the log lines of a single run can be correlated.
+type runLogger struct {
+	runID string
+}
+
+func newRunLogger(runID string) logging.Logger {
+	return &runLogger{runID: runID}
+}
+
+func withRunLogger(ctx context.Context, log logging.Logger) context.Context {
+	return context.WithValue(ctx, runLoggerKey{}, log)
+}
+
+// logger returns the logger of the sync run in the context, or the job logger
+func (v *VaultCredSync) logger(ctx context.Context) logging.Logger {
+	if log, ok := ctx.Value(runLoggerKey{}).(logging.Logger); ok {
+		return log
+	}
+	return v.log
+}
+
+func (l *runLogger) entry() *logrus.Entry {
+	pc, file, line, _ := runtime.Caller(2)
+	return logrus.WithFields(logrus.Fields{
+		"runID":  l.runID,
+		"caller": fmt.Sprintf("%s:%v:%s", file, line, path.Base(runtime.FuncForPC(pc).Name())),
+	})
+}
+
+func (l *runLogger) Infof(format string, args ...interface{}) {
+	l.entry().Infof(format, args...)
+}
+
+func (l *runLogger) Debugf(format string, args ...interface{}) {
+	l.entry().Debugf(format, args...)
+}
+
+func (l *runLogger) Errorf(format string, args ...interface{}) {
+	l.entry().Errorf(format, args...)
+}
+
+func (l *runLogger) Fatalf(format string, args ...interface{}) {
+	l.entry().Fatalf(format, args...)
+}
+
+func (l *runLogger) Info(format string, args ...interface{}) {
+	l.entry().Info(formatArgs(format, args))
+}
+
+func (l *runLogger) Warn(format string, args ...interface{}) {
+	l.entry().Warn(formatArgs(format, args))
+}
+
+func (l *runLogger) Debug(format string, args ...interface{}) {
+	l.entry().Debug(formatArgs(format, args))
+}
+
+func (l *runLogger) Error(format string, args ...interface{}) {
+	l.entry().Error(formatArgs(format, args))
+}
+
+func (l *runLogger) Fatal(format string, args ...interface{}) {
+	l.entry().Fatal(formatArgs(format, args))
+}
+
+func (l *runLogger) Audit(auditType, operation, status, user, format string, args ...interface{}) {
+	l.entry().WithFields(logrus.Fields{
+		"type":      auditType,
+		"operation": operation,
+		"status":    status,
+		"user":      user,
+	}).Infof(format, args...)
+}
+
+func formatArgs(format string, args []interface{}) string {
+	if len(args) > 0 {
+		return fmt.Sprintf("%s %v", format, args)
+	}
+	return format
+}
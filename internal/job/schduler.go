@@ -1,9 +1,11 @@
 package job
 
 import (
+	"sync"
+	"time"
+
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
-	"sync"
 
 	"github.com/intelops/go-common/logging"
 )
@@ -80,6 +82,34 @@ func (t *Scheduler) Stop() {
 	t.log.Infof("Job scheduler stopped")
 }
 
+// parseCronSpec validates the frequency with the cron parser used by the scheduler,
+// plain durations like "30s" are translated to an "@every" spec.
+func parseCronSpec(frequency string) (string, error) {
+	if interval, err := time.ParseDuration(frequency); err == nil {
+		if interval <= 0 {
+			return "", errors.Errorf("frequency %s is not a positive interval", frequency)
+		}
+		frequency = "@every " + interval.String()
+	}
+
+	if _, err := cron.ParseStandard(frequency); err != nil {
+		return "", errors.WithMessagef(err, "frequency %s is not a valid cron spec", frequency)
+	}
+	return frequency, nil
+}
+
+// NextRun returns the time of the next scheduled run of the job, once the scheduler is started
+func (t *Scheduler) NextRun(jobName string) (time.Time, bool) {
+	t.cronMutex.Lock()
+	defer t.cronMutex.Unlock()
+	entryID, ok := t.cronIDs[jobName]
+	if !ok {
+		return time.Time{}, false
+	}
+	next := t.c.Entry(entryID).Next
+	return next, !next.IsZero()
+}
+
 func (t *Scheduler) GetJobs() map[string]jobHandler {
 	t.cronMutex.Lock()
 	defer t.cronMutex.Unlock()
@@ -0,0 +1,118 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrShuttingDown is returned for the sync runs started or left pending after the job shutdown
+var ErrShuttingDown = errors.New("vault credential sync is shutting down")
+
+// shutdownState tracks the in-flight sync runs, so the job can stop dispatching new
+// writes on shutdown and wait for the outstanding ones to finish.
+type shutdownState struct {
+	mutex    sync.Mutex
+	runs     sync.WaitGroup
+	draining chan struct{}
+	abort    chan struct{}
+	// written and pending count the credentials written and left unsynced after the drain started
+	written int64
+	pending int64
+}
+
+func newShutdownState() *shutdownState {
+	return &shutdownState{draining: make(chan struct{}), abort: make(chan struct{})}
+}
+
+// begin registers a sync run, it returns false once the drain has started
+func (s *shutdownState) begin() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.draining:
+		return false
+	default:
+	}
+	s.runs.Add(1)
+	return true
+}
+
+// bind returns a context cancelled when the grace period expires without the runs finishing
+func (s *shutdownState) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.abort:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// context returns a context cancelled when the drain starts
+func (s *shutdownState) context() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.draining
+		cancel()
+	}()
+	return ctx
+}
+
+func (s *shutdownState) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *shutdownState) recordWritten() {
+	if s.isDraining() {
+		atomic.AddInt64(&s.written, 1)
+	}
+}
+
+func (s *shutdownState) recordPending(count int) {
+	atomic.AddInt64(&s.pending, int64(count))
+}
+
+// Shutdown stops dispatching credential writes and waits up to the grace period for the
+// in-flight writes to finish, the runs still active after the grace period are cancelled.
+func (v *VaultCredSync) Shutdown(gracePeriod time.Duration) {
+	s := v.shutdown
+	s.mutex.Lock()
+	if s.isDraining() {
+		s.mutex.Unlock()
+		return
+	}
+	close(s.draining)
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(gracePeriod)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		v.log.Warn(fmt.Sprintf("vault credential sync did not finish within the %s grace period, cancelling in-flight writes",
+			gracePeriod))
+		close(s.abort)
+		<-done
+	}
+	v.closeVaultClient()
+	v.log.Infof("vault credential sync shutdown, %d credentials written and %d pending",
+		atomic.LoadInt64(&s.written), atomic.LoadInt64(&s.pending))
+}
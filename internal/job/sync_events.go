@@ -0,0 +1,72 @@
+package job
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/intelops/vault-cred/internal/client"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	syncSucceededReason = "SyncSucceeded"
+	syncFailedReason    = "SyncFailed"
+	// maxEventMessageLength keeps the event messages of long aggregated errors readable
+	maxEventMessageLength = 1024
+)
+
+type syncEvent struct {
+	eventType string
+	reason    string
+	message   string
+}
+
+// recordSyncEvents records a kubernetes event on the sync secret of the synced keys, a warning
+// event per key that failed to sync or a single normal event when all the keys are synced
+func (v *VaultCredSync) recordSyncEvents(ctx context.Context, tasks []syncTask,
+	sourceObjects map[string]*sourceObject, failedSources map[string][]error) {
+	if !v.conf.K8sEvents || v.DryRun {
+		return
+	}
+
+	taskCounts := map[string]int{}
+	for _, task := range tasks {
+		if sourceObjects[task.source] != nil {
+			taskCounts[task.source]++
+		}
+	}
+	if len(taskCounts) == 0 {
+		return
+	}
+
+	log := v.logger(ctx)
+	k8s, err := client.NewK8SClient(log)
+	if err != nil {
+		log.Errorf("failed to init k8s client for the sync events, %s", err)
+		return
+	}
+
+	for source, count := range taskCounts {
+		object := sourceObjects[source]
+		events := []syncEvent{}
+		if failures := failedSources[source]; len(failures) != 0 {
+			for _, failure := range failures {
+				events = append(events, syncEvent{corev1.EventTypeWarning, syncFailedReason, failure.Error()})
+			}
+		} else {
+			events = append(events, syncEvent{corev1.EventTypeNormal, syncSucceededReason,
+				fmt.Sprintf("%d credentials synced to vault", count)})
+		}
+
+		for _, event := range events {
+			message := event.message
+			if len(message) > maxEventMessageLength {
+				message = message[:maxEventMessageLength]
+			}
+			err := k8s.CreateEvent(ctx, object.kind, object.namespace, object.name, event.eventType, event.reason, message)
+			if err != nil {
+				log.Errorf("failed to record %s event for %s, %s", event.reason, source, err)
+			}
+		}
+	}
+}
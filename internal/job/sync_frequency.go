@@ -0,0 +1,56 @@
+package job
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/robfig/cron/v3"
+)
+
+// syncFrequencyKey of a sync source sets the frequency its credentials are synced at,
+// as a cron spec or an interval, instead of every run of the job
+const syncFrequencyKey = "_sync_frequency"
+
+// sourceSyncSchedule removes the sync frequency key from the source data and returns its schedule,
+// nil when the source has no sync frequency or it is not valid, so the source syncs on every run
+func sourceSyncSchedule(log logging.Logger, source string, data map[string]string) cron.Schedule {
+	frequency, found := data[syncFrequencyKey]
+	if !found {
+		return nil
+	}
+	delete(data, syncFrequencyKey)
+
+	spec, err := parseCronSpec(frequency)
+	if err != nil {
+		log.Warn(fmt.Sprintf("invalid sync frequency of sync source %s, syncing at the default frequency, %s", source, err))
+		return nil
+	}
+	schedule, _ := cron.ParseStandard(spec)
+	return schedule
+}
+
+// sourceDue reports whether the sync source is due as per its schedule since its last sync
+func (v *VaultCredSync) sourceDue(source string, schedule cron.Schedule, now time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+	v.stateMutex.Lock()
+	lastSyncTime, found := v.sourceSyncTimes[source]
+	v.stateMutex.Unlock()
+	return !found || !now.Before(schedule.Next(lastSyncTime))
+}
+
+func (v *VaultCredSync) recordSourceSyncTimes(sources []string, syncTime time.Time, resetGeneration int) {
+	v.stateMutex.Lock()
+	defer v.stateMutex.Unlock()
+	if v.resetGeneration != resetGeneration {
+		return
+	}
+	if v.sourceSyncTimes == nil {
+		v.sourceSyncTimes = map[string]time.Time{}
+	}
+	for _, source := range sources {
+		v.sourceSyncTimes[source] = syncTime
+	}
+}
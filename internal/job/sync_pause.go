@@ -0,0 +1,20 @@
+package job
+
+// syncPausedAnnotation pauses the sync of a kubernetes sync source while set to "true",
+// the sync resumes once the annotation is removed
+const syncPausedAnnotation = "vault-cred/paused"
+
+// annotatedCredentialSource is a credential source with the annotations of its kubernetes object,
+// the annotations are read by the fetch
+type annotatedCredentialSource interface {
+	annotations() map[string]string
+}
+
+// sourcePaused reports whether the fetched source is annotated as paused
+func sourcePaused(source CredentialSource) bool {
+	annotatedSource, ok := source.(annotatedCredentialSource)
+	if !ok {
+		return false
+	}
+	return annotatedSource.annotations()[syncPausedAnnotation] == "true"
+}
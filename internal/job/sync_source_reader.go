@@ -0,0 +1,131 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	syncSourceKindSecret    = "secret"
+	syncSourceKindConfigMap = "configmap"
+	syncSourceKindFile      = "file"
+)
+
+// syncSourceReader reads the sync data from the kubernetes source kind, the keys
+// of every kind are parsed and stored the same way
+type syncSourceReader interface {
+	get(ctx context.Context, name, namespace string) (*client.SecretData, error)
+	list(ctx context.Context, namespace, labelSelector, namePrefix string) ([]*client.SecretData, error)
+	watch(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error)
+}
+
+func newSyncSourceReader(k8s *client.K8SClient, sourceKind string) (syncSourceReader, error) {
+	switch sourceKind {
+	case syncSourceKindSecret, "":
+		return &secretReader{k8s: k8s}, nil
+	case syncSourceKindConfigMap:
+		return &configMapReader{k8s: k8s}, nil
+	default:
+		return nil, errors.Errorf("sync source kind %s not supported", sourceKind)
+	}
+}
+
+// retryingReader retries the reads of the sync sources while the kubernetes api server is unreachable,
+// and retries not found sources as configured to tolerate the lag of a cached client
+type retryingReader struct {
+	reader syncSourceReader
+	v      *VaultCredSync
+}
+
+func (r *retryingReader) get(ctx context.Context, name, namespace string) (secret *client.SecretData, err error) {
+	for attempt := 0; ; attempt++ {
+		err = r.v.k8sOp(ctx, func() (err error) {
+			secret, err = r.reader.get(ctx, name, namespace)
+			return
+		})
+		if err == nil && attempt > 0 {
+			r.v.logger(ctx).Infof("sync source %s/%s found after %d not found retries", namespace, name, attempt)
+		}
+		notFound := errors.Is(err, client.ErrSecretNotFound) || errors.Is(err, client.ErrConfigMapNotFound)
+		if !notFound || attempt >= r.v.conf.K8sNotFoundRetries {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.v.conf.K8sNotFoundRetryDelay):
+		}
+	}
+}
+
+func (r *retryingReader) list(ctx context.Context, namespace, labelSelector, namePrefix string) (secrets []*client.SecretData, err error) {
+	err = r.v.k8sOp(ctx, func() (err error) {
+		secrets, err = r.reader.list(ctx, namespace, labelSelector, namePrefix)
+		return
+	})
+	return
+}
+
+func (r *retryingReader) watch(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error) {
+	return r.reader.watch(ctx, namespace, name, labelSelector)
+}
+
+type secretReader struct {
+	k8s *client.K8SClient
+}
+
+func (r *secretReader) get(ctx context.Context, name, namespace string) (*client.SecretData, error) {
+	return r.k8s.GetSecret(ctx, name, namespace)
+}
+
+func (r *secretReader) list(ctx context.Context, namespace, labelSelector, namePrefix string) ([]*client.SecretData, error) {
+	return r.k8s.ListSecrets(ctx, namespace, labelSelector, namePrefix)
+}
+
+func (r *secretReader) watch(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error) {
+	return r.k8s.WatchSecrets(ctx, namespace, name, labelSelector)
+}
+
+type configMapReader struct {
+	k8s *client.K8SClient
+}
+
+func (r *configMapReader) get(ctx context.Context, name, namespace string) (*client.SecretData, error) {
+	configMap, err := r.k8s.GetConfigMap(ctx, name, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return configMapSyncData(configMap), nil
+}
+
+func (r *configMapReader) list(ctx context.Context, namespace, labelSelector, namePrefix string) ([]*client.SecretData, error) {
+	configMaps, err := r.k8s.ListConfigMaps(ctx, namespace, labelSelector, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	syncData := make([]*client.SecretData, 0, len(configMaps))
+	for _, configMap := range configMaps {
+		syncData = append(syncData, configMapSyncData(configMap))
+	}
+	return syncData, nil
+}
+
+func (r *configMapReader) watch(ctx context.Context, namespace, name, labelSelector string) (watch.Interface, error) {
+	return r.k8s.WatchConfigMaps(ctx, namespace, name, labelSelector)
+}
+
+func configMapSyncData(configMap *client.ConfigMapData) *client.SecretData {
+	return &client.SecretData{
+		Name:            configMap.Name,
+		Namespace:       configMap.Namespace,
+		Data:            configMap.Data,
+		LastUpdatedTime: configMap.LastUpdatedTime,
+		Labels:          configMap.Labels,
+		Annotations:     configMap.Annotations,
+	}
+}
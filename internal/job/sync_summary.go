@@ -0,0 +1,226 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SyncSummary counts the credentials of a sync run by outcome
+type SyncSummary struct {
+	Written int `json:"written"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// SyncResult is the structured outcome of a sync run
+type SyncResult struct {
+	SyncSummary
+	// ByType counts the credentials by credential type
+	ByType   map[string]SyncSummary `json:"byType"`
+	Failures []SyncFailure          `json:"failures,omitempty"`
+	Duration time.Duration          `json:"duration"`
+	// SourceUpdatedTimes holds the last updated time of every sync source read, by source id
+	SourceUpdatedTimes map[string]time.Time `json:"sourceUpdatedTimes,omitempty"`
+	// Clusters counts the credential writes by vault cluster address, when fallback clusters are configured
+	Clusters map[string]SyncSummary `json:"clusters,omitempty"`
+	// Aborted describes why the run stopped before syncing all the secret keys, empty when it did not
+	Aborted string `json:"aborted,omitempty"`
+	// Collisions are the secret keys resolved to a vault path already written by another key of the run
+	Collisions []PathCollision `json:"collisions,omitempty"`
+}
+
+// PathCollision identifies two secret keys of a sync run resolved to the same vault path
+type PathCollision struct {
+	Path         string `json:"path"`
+	Identifier   string `json:"identifier"`
+	DuplicateKey string `json:"duplicateKey"`
+}
+
+// SyncFailure identifies a secret key that failed to sync
+type SyncFailure struct {
+	Identifier     string `json:"identifier"`
+	CredentialType string `json:"credentialType"`
+	Error          string `json:"error"`
+}
+
+// String describes the sync result in a single human readable line
+func (r SyncResult) String() string {
+	types := make([]string, 0, len(r.ByType))
+	for credentialType := range r.ByType {
+		types = append(types, credentialType)
+	}
+	sort.Strings(types)
+
+	byType := make([]string, 0, len(types))
+	for _, credentialType := range types {
+		s := r.ByType[credentialType]
+		byType = append(byType, fmt.Sprintf("%s: %d/%d/%d", credentialType, s.Written, s.Skipped, s.Failed))
+	}
+	summary := fmt.Sprintf("%d written, %d skipped, %d failed in %s [%s]",
+		r.Written, r.Skipped, r.Failed, r.Duration.Round(time.Millisecond), strings.Join(byType, ", "))
+	if r.Aborted != "" {
+		summary += ", " + r.Aborted
+	}
+	return summary
+}
+
+type runSummaryKey struct{}
+
+type runSummary struct {
+	mutex  sync.Mutex
+	result SyncResult
+	// claimedPaths holds the secret key of every vault path written in the run
+	claimedPaths map[vaultPath]string
+}
+
+func withRunSummary(ctx context.Context) (context.Context, *runSummary) {
+	s := &runSummary{result: SyncResult{ByType: map[string]SyncSummary{}, SourceUpdatedTimes: map[string]time.Time{},
+		Clusters: map[string]SyncSummary{}}, claimedPaths: map[vaultPath]string{}}
+	return context.WithValue(ctx, runSummaryKey{}, s), s
+}
+
+func runSummaryFrom(ctx context.Context) (*runSummary, bool) {
+	s, ok := ctx.Value(runSummaryKey{}).(*runSummary)
+	return s, ok
+}
+
+// recordOutcome adds to the summary of the sync run in the context, if any
+func recordOutcome(ctx context.Context, credentialType string, written, skipped, failed int) {
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.result.Written += written
+	s.result.Skipped += skipped
+	s.result.Failed += failed
+
+	typeSummary := s.result.ByType[credentialType]
+	typeSummary.Written += written
+	typeSummary.Skipped += skipped
+	typeSummary.Failed += failed
+	s.result.ByType[credentialType] = typeSummary
+}
+
+// recordFailure records the secret key that failed to sync in the summary of the sync run
+func recordFailure(ctx context.Context, task syncTask, err error) {
+	credentialType := credentialTypeLabel(task.key)
+	recordOutcome(ctx, credentialType, 0, 0, 1)
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.result.Failures = append(s.result.Failures,
+		SyncFailure{Identifier: task.id(), CredentialType: credentialType, Error: err.Error()})
+}
+
+// recordClusterOutcome counts the credential write to the vault cluster in the summary of the sync run
+func recordClusterOutcome(ctx context.Context, address string, err error) {
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	clusterSummary := s.result.Clusters[address]
+	if err != nil {
+		clusterSummary.Failed++
+	} else {
+		clusterSummary.Written++
+	}
+	s.result.Clusters[address] = clusterSummary
+}
+
+func recordSourceUpdated(ctx context.Context, source string, updatedTime time.Time) {
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.result.SourceUpdatedTimes[source] = updatedTime
+}
+
+// claimPath claims the vault path for the secret key in the sync run, when the path was claimed by
+// another key it records the collision and returns the key which claimed it
+func claimPath(ctx context.Context, secretPath vaultPath, id string) (string, bool) {
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return "", true
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	claimedBy, found := s.claimedPaths[secretPath]
+	if !found || claimedBy == id {
+		s.claimedPaths[secretPath] = id
+		return "", true
+	}
+	s.result.Collisions = append(s.result.Collisions,
+		PathCollision{Path: secretPath.String(), Identifier: claimedBy, DuplicateKey: id})
+	return claimedBy, false
+}
+
+// recordAborted records in the summary of the sync run why the run was aborted
+func recordAborted(ctx context.Context, reason string) {
+	s, ok := runSummaryFrom(ctx)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.result.Aborted = reason
+}
+
+func (s *runSummary) get() SyncResult {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	result := s.result
+	result.ByType = make(map[string]SyncSummary, len(s.result.ByType))
+	for credentialType, typeSummary := range s.result.ByType {
+		result.ByType[credentialType] = typeSummary
+	}
+	result.SourceUpdatedTimes = make(map[string]time.Time, len(s.result.SourceUpdatedTimes))
+	for source, updatedTime := range s.result.SourceUpdatedTimes {
+		result.SourceUpdatedTimes[source] = updatedTime
+	}
+	result.Clusters = make(map[string]SyncSummary, len(s.result.Clusters))
+	for address, clusterSummary := range s.result.Clusters {
+		result.Clusters[address] = clusterSummary
+	}
+	result.Failures = append([]SyncFailure(nil), s.result.Failures...)
+	result.Collisions = append([]PathCollision(nil), s.result.Collisions...)
+	return result
+}
+
+// runWithResult performs the sync run and returns its result
+func (v *VaultCredSync) runWithResult(ctx context.Context) (SyncResult, error) {
+	ctx, summary := withRunSummary(ctx)
+	ctx, span := v.startSpan(ctx, syncRunSpanName, nil)
+	startTime := time.Now()
+	err := v.runE(ctx)
+	result := summary.get()
+	result.Duration = time.Since(startTime)
+	v.recordRunResult(result)
+	span.SetAttribute("written", strconv.Itoa(result.Written))
+	span.SetAttribute("skipped", strconv.Itoa(result.Skipped))
+	span.SetAttribute("failed", strconv.Itoa(result.Failed))
+	span.End(err)
+	v.notifyWebhook(ctx, result, err)
+	return result, err
+}
+
+// SyncNow performs a credential sync immediately and returns the result of the run
+func (v *VaultCredSync) SyncNow(ctx context.Context) (SyncResult, error) {
+	ctx = newRunContext(ctx)
+	log := v.logger(ctx)
+	log.Infof("started on demand vault credential sync")
+	return v.runWithResult(ctx)
+}
@@ -0,0 +1,119 @@
+package job
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	syncModeCron  = "cron"
+	syncModeWatch = "watch"
+
+	watchRetryDelay = 10 * time.Second
+)
+
+type sourceChangedKey struct{}
+
+// sourceChanged reports whether the sync run was triggered by a change of a sync secret, the
+// secret last updated time is the creation time so it can't tell the secret data changed
+func sourceChanged(ctx context.Context) bool {
+	changed, _ := ctx.Value(sourceChangedKey{}).(bool)
+	return changed
+}
+
+// StartWatch watches the sync secrets in the watch sync mode and triggers a sync run on every change,
+// while the cron spec keeps the periodic full reconciliation. The sync namespaces are resolved when
+// the watch starts, namespaces added later are synced by the periodic reconciliation.
+func (v *VaultCredSync) StartWatch() error {
+	if v.conf.SyncMode != syncModeWatch {
+		return nil
+	}
+
+	k8s, err := client.NewK8SClient(v.log)
+	if err != nil {
+		return errors.WithMessage(err, "failed to init k8s client")
+	}
+	reader, err := newSyncSourceReader(k8s, v.conf.SyncSourceKind)
+	if err != nil {
+		return err
+	}
+	ctx := v.shutdown.context()
+	namespaces, _, err := v.syncSecretNamespaces(ctx, k8s)
+	if err != nil {
+		return err
+	}
+
+	trigger := make(chan struct{}, 1)
+	for _, namespace := range namespaces {
+		go v.watchNamespace(ctx, reader, namespace, trigger)
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-trigger:
+				v.run(context.WithValue(newRunContext(context.Background()), sourceChangedKey{}, true))
+			}
+		}
+	}()
+	v.log.Infof("watching the sync secrets of %d namespaces", len(namespaces))
+	return nil
+}
+
+// watchNamespace triggers a sync run for every change of a sync secret of the namespace,
+// the watch is restarted when the api server closes it
+func (v *VaultCredSync) watchNamespace(ctx context.Context, reader syncSourceReader, namespace string, trigger chan<- struct{}) {
+	name := ""
+	if !v.multiSecret() {
+		name = v.conf.VaultCredSyncSecretName
+	}
+
+	for {
+		w, err := reader.watch(ctx, namespace, name, v.conf.SyncSecretSelector)
+		if err != nil {
+			v.log.Errorf("failed to watch sync secrets, %s", err)
+		} else {
+			v.forwardWatchEvents(ctx, w, trigger)
+			w.Stop()
+		}
+
+		timer := time.NewTimer(watchRetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (v *VaultCredSync) forwardWatchEvents(ctx context.Context, w watch.Interface, trigger chan<- struct{}) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return
+			}
+			object, err := meta.Accessor(event.Object)
+			if err != nil || !strings.HasPrefix(object.GetName(), v.conf.SyncSecretPrefix) {
+				continue
+			}
+
+			v.log.Debugf("sync secret %s/%s %s", object.GetNamespace(), object.GetName(), strings.ToLower(string(event.Type)))
+			select {
+			case trigger <- struct{}{}:
+			default:
+				// a sync run is already pending, it reads the latest secrets
+			}
+		}
+	}
+}
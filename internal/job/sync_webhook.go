@@ -0,0 +1,96 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// webhookSignatureHeader carries the hex HMAC-SHA256 of the webhook body keyed with the webhook secret
+const webhookSignatureHeader = "X-Vault-Cred-Signature"
+
+// webhookPayload is the sync result posted to the webhook
+type webhookPayload struct {
+	SyncResult
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// webhookStatusError is a webhook response with a non 2xx status
+type webhookStatusError struct {
+	statusCode int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.statusCode)
+}
+
+// isWebhookRetryable retries the webhook on connection errors, server errors and rate limiting
+func isWebhookRetryable(err error) bool {
+	var statusErr *webhookStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode >= http.StatusInternalServerError || statusErr.statusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// notifyWebhook posts the result of the sync run to the configured webhook,
+// only for the failed runs when configured so
+func (v *VaultCredSync) notifyWebhook(ctx context.Context, result SyncResult, runErr error) {
+	if v.conf.WebhookURL == "" || (runErr == nil && v.conf.WebhookOnFailureOnly) {
+		return
+	}
+	log := v.logger(ctx)
+	payload := webhookPayload{SyncResult: result, Success: runErr == nil}
+	if runErr != nil {
+		payload.Error = runErr.Error()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Errorf("failed to encode sync webhook payload, %v", err)
+		return
+	}
+
+	maxElapsedTime := time.Duration(v.conf.WebhookMaxAttempts) * v.conf.WebhookTimeout
+	err = retryWithBackoffIf(ctx, log, v.conf.WebhookMaxAttempts, maxElapsedTime, isWebhookRetryable, func() error {
+		return v.postWebhook(ctx, body)
+	})
+	if err != nil {
+		log.Errorf("failed to post sync result to webhook, %v", err)
+		return
+	}
+	log.Debugf("posted sync result to webhook")
+}
+
+func (v *VaultCredSync) postWebhook(ctx context.Context, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, v.conf.WebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.conf.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if v.conf.WebhookSecret != "" {
+		mac := hmac.New(sha256.New, []byte(v.conf.WebhookSecret))
+		mac.Write(body)
+		req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return &webhookStatusError{statusCode: resp.StatusCode}
+	}
+	return nil
+}
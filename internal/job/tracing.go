@@ -0,0 +1,38 @@
+package job
+
+import "context"
+
+// Tracer starts the spans of the sync runs and credential writes. No tracer is set by default,
+// an OpenTelemetry tracer can be adapted to it by the embedders of the sync job.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span is a span started by the Tracer, ended with the error of the traced operation
+type Span interface {
+	SetAttribute(key, value string)
+	End(err error)
+}
+
+const (
+	syncRunSpanName         = "vault-cred.sync"
+	credentialWriteSpanName = "vault-cred.write"
+)
+
+// SetTracer sets the tracer of the sync runs and credential writes
+func (v *VaultCredSync) SetTracer(tracer Tracer) {
+	v.tracer = tracer
+}
+
+func (v *VaultCredSync) startSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	if v.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return v.tracer.Start(ctx, name, attributes)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key, value string) {}
+
+func (noopSpan) End(err error) {}
@@ -0,0 +1,36 @@
+package job
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError is returned when required attributes of the credential json are empty
+type ValidationError struct {
+	SecretKey      string
+	CredentialType string
+	// EmptyFields are the json names of the required attributes found empty
+	EmptyFields []string
+}
+
+// newValidationError returns a ValidationError for the empty fields of the required fields by
+// json name, or nil when none of them is empty
+func newValidationError(secretKey, credentialType string, requiredFields map[string]string) error {
+	emptyFields := []string{}
+	for name, val := range requiredFields {
+		if len(val) == 0 {
+			emptyFields = append(emptyFields, name)
+		}
+	}
+	if len(emptyFields) == 0 {
+		return nil
+	}
+	sort.Strings(emptyFields)
+	return &ValidationError{SecretKey: secretKey, CredentialType: credentialType, EmptyFields: emptyFields}
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("credential attributes %s are empty for %s %s secret data",
+		strings.Join(e.EmptyFields, ", "), e.SecretKey, e.CredentialType)
+}
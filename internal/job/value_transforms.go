@@ -0,0 +1,65 @@
+package job
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	valueTransformNone        = "none"
+	valueTransformTrim        = "trim"
+	valueTransformTrimNewline = "trim-newline"
+	valueTransformUpper       = "upper"
+	valueTransformLower       = "lower"
+	valueTransformsSeparator  = "+"
+)
+
+var valueTransformFuncs = map[string]func(string) string{
+	valueTransformTrim:        strings.TrimSpace,
+	valueTransformTrimNewline: func(val string) string { return strings.TrimRight(val, "\r\n") },
+	valueTransformUpper:       strings.ToUpper,
+	valueTransformLower:       strings.ToLower,
+}
+
+// defaultValueTransforms strip the trailing newlines of the certificate material,
+// which kubectl created secrets often carry
+var defaultValueTransforms = map[string][]string{
+	caDataKey:   {valueTransformTrimNewline},
+	certDataKey: {valueTransformTrimNewline},
+	keyDataKey:  {valueTransformTrimNewline},
+}
+
+// parseValueTransforms parses the transforms configured per credential field as field:transform,
+// several transforms are chained with a +, e.g. password:trim+lower, and none disables
+// the default transforms of a field
+func parseValueTransforms(transforms map[string]string) (map[string][]string, error) {
+	fieldTransforms := map[string][]string{}
+	for field, names := range defaultValueTransforms {
+		fieldTransforms[field] = names
+	}
+	for field, configured := range transforms {
+		names := []string{}
+		for _, name := range strings.Split(configured, valueTransformsSeparator) {
+			if name == valueTransformNone {
+				continue
+			}
+			if _, found := valueTransformFuncs[name]; !found {
+				return nil, errors.Errorf("value transform %s of credential field %s not supported", name, field)
+			}
+			names = append(names, name)
+		}
+		fieldTransforms[field] = names
+	}
+	return fieldTransforms, nil
+}
+
+// transformCredentialValues applies the transforms of the credential fields in place
+func (v *VaultCredSync) transformCredentialValues(cred map[string]string) {
+	for field, val := range cred {
+		for _, name := range v.valueTransforms[field] {
+			val = valueTransformFuncs[name](val)
+		}
+		cred[field] = val
+	}
+}
@@ -0,0 +1,70 @@
+package job
+
+import "testing"
+
+const testPEM = "-----BEGIN CERTIFICATE-----\nMIIBszCCAVmgAwIBAgIUQ\nZ2VuZXJhdGVk\n-----END CERTIFICATE-----"
+
+func TestDefaultValueTransforms(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		value string
+		want  string
+	}{
+		{name: "trailing newline", field: certDataKey, value: "cert\n", want: "cert"},
+		{name: "trailing crlf", field: certDataKey, value: "cert\r\n", want: "cert"},
+		{name: "several trailing newlines", field: keyDataKey, value: "key\n\r\n\n", want: "key"},
+		{name: "interior newlines kept", field: caDataKey, value: "line1\nline2\r\nline3\n", want: "line1\nline2\r\nline3"},
+		{name: "leading newline kept", field: caDataKey, value: "\nca", want: "\nca"},
+		{name: "trailing spaces kept", field: certDataKey, value: "cert \n", want: "cert "},
+		{name: "pem", field: certDataKey, value: testPEM + "\n", want: testPEM},
+		{name: "pem crlf", field: keyDataKey, value: testPEM + "\r\n", want: testPEM},
+		{name: "pem without newline", field: caDataKey, value: testPEM, want: testPEM},
+		{name: "other fields untouched", field: "password", value: "secret\n", want: "secret\n"},
+	}
+	transforms, err := parseValueTransforms(nil)
+	if err != nil {
+		t.Fatalf("parseValueTransforms() error = %v", err)
+	}
+	v := &VaultCredSync{valueTransforms: transforms}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cred := map[string]string{tt.field: tt.value}
+			v.transformCredentialValues(cred)
+			if cred[tt.field] != tt.want {
+				t.Errorf("%s transformed to %q, want %q", tt.field, cred[tt.field], tt.want)
+			}
+		})
+	}
+}
+
+func TestParseValueTransforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		transforms map[string]string
+		field      string
+		value      string
+		want       string
+		wantErr    bool
+	}{
+		{name: "chained", transforms: map[string]string{"password": "trim+lower"}, field: "password", value: " SeCret\n", want: "secret"},
+		{name: "none disables the default", transforms: map[string]string{certDataKey: "none"}, field: certDataKey, value: "cert\n", want: "cert\n"},
+		{name: "not supported", transforms: map[string]string{"password": "reverse"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transforms, err := parseValueTransforms(tt.transforms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseValueTransforms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			cred := map[string]string{tt.field: tt.value}
+			(&VaultCredSync{valueTransforms: transforms}).transformCredentialValues(cred)
+			if cred[tt.field] != tt.want {
+				t.Errorf("%s transformed to %q, want %q", tt.field, cred[tt.field], tt.want)
+			}
+		})
+	}
+}
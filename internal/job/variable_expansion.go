@@ -0,0 +1,154 @@
+package job
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+type credentialVariablesKey struct{}
+
+// credentialVariables holds the variables of the variables secret for a sync run, the secret is
+// read once on the first credential referencing a variable and shared by the rest of the run
+type credentialVariables struct {
+	once sync.Once
+	load func() (map[string]string, error)
+	data map[string]string
+	err  error
+}
+
+func (v *VaultCredSync) withCredentialVariables(ctx context.Context) context.Context {
+	variables := &credentialVariables{load: func() (map[string]string, error) {
+		return v.variablesSecretData(ctx)
+	}}
+	return context.WithValue(ctx, credentialVariablesKey{}, variables)
+}
+
+// credentialVariablesFrom returns the variables of the sync run, or variables read for the
+// single credential outside of a sync run
+func (v *VaultCredSync) credentialVariablesFrom(ctx context.Context) *credentialVariables {
+	if variables, ok := ctx.Value(credentialVariablesKey{}).(*credentialVariables); ok && variables != nil {
+		return variables
+	}
+	return v.withCredentialVariables(ctx).Value(credentialVariablesKey{}).(*credentialVariables)
+}
+
+func (c *credentialVariables) get() (map[string]string, error) {
+	c.once.Do(func() {
+		c.data, c.err = c.load()
+	})
+	return c.data, c.err
+}
+
+// expandCredentialVariables expands the ${VAR} references of the credential values in place,
+// the variables are read from the configured variables secret, else from the environment
+// variables with the configured prefix, so the secrets of the job environment are not exposed
+func (v *VaultCredSync) expandCredentialVariables(variables *credentialVariables, cred map[string]string) error {
+	if !v.conf.ExpandVariables {
+		return nil
+	}
+
+	var secretVariables map[string]string
+	for key, val := range cred {
+		if !strings.Contains(val, "$") {
+			continue
+		}
+		if secretVariables == nil && v.conf.VariablesSecretName != "" {
+			var err error
+			if secretVariables, err = variables.get(); err != nil {
+				return err
+			}
+		}
+
+		expanded, err := expandVariables(val, func(name string) (string, error) {
+			if v.conf.VariablesSecretName != "" {
+				value, found := secretVariables[name]
+				if !found {
+					return "", errors.Errorf("variable %s is not defined", name)
+				}
+				return value, nil
+			}
+			return v.lookupEnvVariable(name)
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "failed to expand variables of credential %s", key)
+		}
+		cred[key] = expanded
+	}
+	return nil
+}
+
+// lookupEnvVariable returns the environment variable, only the variables with the configured
+// prefix are read and no variable is read when the prefix is empty
+func (v *VaultCredSync) lookupEnvVariable(name string) (string, error) {
+	if v.conf.VariablesEnvPrefix == "" {
+		return "", errors.Errorf("variable %s is not allowed, no environment variable prefix is configured", name)
+	}
+	if !strings.HasPrefix(name, v.conf.VariablesEnvPrefix) {
+		return "", errors.Errorf("variable %s is not allowed, environment variables must start with %s",
+			name, v.conf.VariablesEnvPrefix)
+	}
+	value, found := os.LookupEnv(name)
+	if !found {
+		return "", errors.Errorf("variable %s is not defined", name)
+	}
+	return value, nil
+}
+
+func (v *VaultCredSync) variablesSecretData(ctx context.Context) (map[string]string, error) {
+	k8s, err := v.k8sClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var secret *client.SecretData
+	err = v.k8sOp(ctx, func() (err error) {
+		secret, err = k8s.GetSecret(ctx, v.conf.VariablesSecretName, v.conf.VaultSecretNameSpace)
+		return
+	})
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to read variables secret %s/%s",
+			v.conf.VaultSecretNameSpace, v.conf.VariablesSecretName)
+	}
+	return secret.Data, nil
+}
+
+// expandVariables replaces the ${VAR} references of the value with the variable values,
+// $$ is a literal $ and a $ not followed by { or $ is kept as is
+func expandVariables(value string, lookup func(string) (string, error)) (string, error) {
+	var expanded strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			expanded.WriteByte(value[i])
+			continue
+		}
+
+		switch value[i+1] {
+		case '$':
+			expanded.WriteByte('$')
+			i++
+		case '{':
+			end := strings.IndexByte(value[i+2:], '}')
+			if end < 0 {
+				return "", errors.Errorf("unterminated variable reference at offset %d", i)
+			}
+			name := value[i+2 : i+2+end]
+			if name == "" {
+				return "", errors.Errorf("empty variable reference at offset %d", i)
+			}
+			val, err := lookup(name)
+			if err != nil {
+				return "", err
+			}
+			expanded.WriteString(val)
+			i += end + 2
+		default:
+			expanded.WriteByte('$')
+		}
+	}
+	return expanded.String(), nil
+}
@@ -0,0 +1,81 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/intelops/vault-cred/config"
+)
+
+func TestExpandCredentialVariablesReadsSecretOnce(t *testing.T) {
+	v := &VaultCredSync{conf: config.VaultEnv{ExpandVariables: true, VariablesSecretName: "vault-cred-variables"}}
+	loads := 0
+	variables := &credentialVariables{load: func() (map[string]string, error) {
+		loads++
+		return map[string]string{"DB_HOST": "db.internal", "DB_USER": "payments"}, nil
+	}}
+
+	creds := []map[string]string{
+		{"host": "${DB_HOST}", "user": "${DB_USER}"},
+		{"url": "postgres://${DB_USER}@${DB_HOST}:5432", "price": "$$5"},
+		{"password": "plain"},
+	}
+	for _, cred := range creds {
+		if err := v.expandCredentialVariables(variables, cred); err != nil {
+			t.Fatalf("expandCredentialVariables() error = %v", err)
+		}
+	}
+
+	if loads != 1 {
+		t.Errorf("variables secret read %d times, want 1 per run", loads)
+	}
+	if creds[0]["host"] != "db.internal" || creds[0]["user"] != "payments" {
+		t.Errorf("first credential expanded to %v", creds[0])
+	}
+	if creds[1]["url"] != "postgres://payments@db.internal:5432" || creds[1]["price"] != "$5" {
+		t.Errorf("second credential expanded to %v", creds[1])
+	}
+}
+
+func TestExpandCredentialVariablesWithoutReferences(t *testing.T) {
+	v := &VaultCredSync{conf: config.VaultEnv{ExpandVariables: true, VariablesSecretName: "vault-cred-variables"}}
+	variables := &credentialVariables{load: func() (map[string]string, error) {
+		t.Fatal("variables secret read for a credential without variable references")
+		return nil, nil
+	}}
+	if err := v.expandCredentialVariables(variables, map[string]string{"password": "plain"}); err != nil {
+		t.Fatalf("expandCredentialVariables() error = %v", err)
+	}
+}
+
+func TestExpandCredentialVariablesFromEnv(t *testing.T) {
+	t.Setenv("VAULT_CRED_VAR_DB_HOST", "db.internal")
+	t.Setenv("VAULT_TOKEN", "root-token")
+	tests := []struct {
+		name    string
+		prefix  string
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{name: "prefixed variable", prefix: "VAULT_CRED_VAR_", value: "${VAULT_CRED_VAR_DB_HOST}:5432", want: "db.internal:5432"},
+		{name: "variable without prefix rejected", prefix: "VAULT_CRED_VAR_", value: "${VAULT_TOKEN}", wantErr: true},
+		{name: "undefined prefixed variable", prefix: "VAULT_CRED_VAR_", value: "${VAULT_CRED_VAR_MISSING}", wantErr: true},
+		{name: "no prefix rejects every variable", prefix: "", value: "${VAULT_CRED_VAR_DB_HOST}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &VaultCredSync{conf: config.VaultEnv{ExpandVariables: true, VariablesEnvPrefix: tt.prefix}}
+			cred := map[string]string{"host": tt.value}
+			err := v.expandCredentialVariables(&credentialVariables{}, cred)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expandCredentialVariables() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cred["host"] != tt.want {
+				t.Errorf("expanded to %q, want %q", cred["host"], tt.want)
+			}
+			if tt.wantErr && cred["host"] != tt.value {
+				t.Errorf("value changed to %q on a rejected variable", cred["host"])
+			}
+		})
+	}
+}
@@ -2,14 +2,22 @@ package job
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/hashicorp/go-multierror"
 	"github.com/intelops/go-common/logging"
 	"github.com/intelops/vault-cred/config"
 	"github.com/intelops/vault-cred/internal/api"
 	"github.com/intelops/vault-cred/internal/client"
+	"github.com/intelops/vault-cred/internal/metrics"
 	"github.com/pkg/errors"
 )
 
@@ -20,122 +28,843 @@ const (
 	caDataKey                    = "ca.pem"
 	certDataKey                  = "cert.crt"
 	keyDataKey                   = "key.key"
+	fullChainDataKey             = "fullchain.pem"
 	serviceCredentialUserNameKey = "userName"
 	serviceCredentialPasswordKey = "password"
+	certEncodingPlain            = "plain"
+	certEncodingBase64           = "base64"
+	syncedByMetadataKey          = "synced_by"
+	syncedByMetadataValue        = "vault-cred"
+	syncedAtMetadataKey          = "synced_at"
+	sourceSecretMetadataKey      = "source_secret"
+	sourceKeyMetadataKey         = "source_key"
+	// transitCiphertextPrefix marks the secret values encrypted with the vault transit engine
+	transitCiphertextPrefix  = "vault:v"
+	defaultIdentifierLength  = 16
+	defaultIdentifierCharset = "abcdefghijklmnopqrstuvwxyz0123456789"
+	// credentialEncodingKey marks the encoding of the credential values stored in vault
+	credentialEncodingKey = "_encoding"
 )
 
+// ErrVaultSealed is returned when the credential sync is skipped as vault is sealed
+var ErrVaultSealed = errors.New("vault is sealed")
+
+// ErrTooManyErrors is returned when a sync run is aborted on reaching the maximum errors of a run
+var ErrTooManyErrors = errors.New("too many credential sync errors")
+
 type CertificateData struct {
 	EntityName      string `json:"entityName"`
-	CertIndentifier string `json:"certIndetifier"`
+	CertIndentifier string `json:"certIdentifier"`
 	CACert          string `json:"caCert"`
 	Key             string `json:"key"`
 	Cert            string `json:"cert"`
+	// Encoding of the certificate material, plain or base64, defaults to plain
+	Encoding string `json:"encoding"`
 }
 
 type ServiceCredentail struct {
 	EntityName      string            `json:"entityName"`
-	CredIndentifier string            `json:"credIndetifier"`
+	CredIndentifier string            `json:"credIdentifier"`
 	UserName        string            `json:"userName"`
 	Password        string            `json:"password"`
 	AdditionalData  map[string]string `json:"additionalData"`
+	// Rotate generates the password instead of using the given one, and rotates it every RotationInterval
+	Rotate           bool   `json:"rotate"`
+	RotationInterval string `json:"rotationInterval"`
 }
 type GenericCredential struct {
-	CredentialType  string            `json:"credentialType"`
-	EntityName      string            `json:"entityName"`
-	CredIndentifier string            `json:"credIndetifier"`
-	Credential      map[string]string `json:"credential"`
+	CredentialType  string `json:"credentialType"`
+	EntityName      string `json:"entityName"`
+	CredIndentifier string `json:"credIdentifier"`
+	// Credential values are strings, nested objects are stored as per the nested encoding config
+	Credential map[string]interface{} `json:"credential"`
+	// Binary marks the credential values as base64 encoded binary data
+	Binary bool `json:"binary"`
+	// TTL is the duration after which vault deletes the credential versions, e.g. 24h
+	TTL string `json:"ttl,omitempty"`
 }
 type VaultCredSync struct {
-	log             logging.Logger
-	conf            config.VaultEnv
-	frequency       string
-	lastUpdatedTime *time.Time
+	log       logging.Logger
+	conf      config.VaultEnv
+	frequency string
+	// lastUpdatedTimes holds the last updated time of every sync secret synced, by namespace/name
+	lastUpdatedTimes map[string]time.Time
+	// DryRun only logs the vault writes the sync would perform
+	DryRun bool
+	// ForceResync writes every credential on each run, even when its source is unchanged
+	ForceResync bool
+	// syncedPaths maps each secret key to the vault path written for it on the previous run
+	syncedPaths map[string]vaultPath
+	// sourceSyncTimes holds the last sync time of the sync sources with their own sync frequency
+	sourceSyncTimes map[string]time.Time
+	// resetGeneration counts the resets, so a run does not record its state over a reset made meanwhile
+	resetGeneration int
+	// stateMutex guards lastUpdatedTimes, syncedPaths, sourceSyncTimes and resetGeneration
+	stateMutex      sync.Mutex
+	credentialTypes *credentialTypeRegistry
+	credentialCache *client.CredentialCache
+	startTime       time.Time
+	statusMutex     sync.Mutex
+	status          SyncStatus
+	// sources replace the kubernetes sync secrets when set
+	sources  map[string]CredentialSource
+	shutdown *shutdownState
+	// jitter delays every scheduled run to spread the replicas running the same cron spec
+	jitter time.Duration
+	// runMutex serializes the sync runs of the cron, the watch and the on demand triggers
+	runMutex sync.Mutex
+	tracer   Tracer
+	// keyRoutes route the credentials of the matching secret keys to their own mount and path
+	keyRoutes []keyRoute
+	// valueTransforms are the transforms applied to the values of each credential field
+	valueTransforms map[string][]string
+	// vc is the vault client shared by the runs and vaultAddress the address it was created
+	// for, guarded by vaultClientMutex
+	vc               *client.VaultClient
+	vaultAddress     string
+	vaultClientMutex sync.Mutex
+	// k8s is the kubernetes client of the health checks, guarded by k8sClientMutex
+	k8s            *client.K8SClient
+	k8sClientMutex sync.Mutex
 }
 
 func NewVaultCredSync(log logging.Logger, frequency string) (*VaultCredSync, error) {
+	frequency, err := parseCronSpec(frequency)
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid vault credential sync frequency")
+	}
+
+	v, err := newVaultCredSync(log)
+	if err != nil {
+		return nil, err
+	}
+	v.frequency = frequency
+	v.jitter = hostJitter(v.conf.SyncJitterWindow)
+	if v.jitter > 0 {
+		log.Infof("vault credential sync runs are delayed by a jitter of %s", v.jitter)
+	}
+	return v, nil
+}
+
+func newVaultCredSync(log logging.Logger) (*VaultCredSync, error) {
 	conf, err := config.GetVaultEnv()
 	if err != nil {
 		return nil, err
 	}
-	return &VaultCredSync{
-		log:       log,
-		frequency: frequency,
-		conf:      conf,
-	}, nil
+
+	if err := client.ValidateTLSConfig(conf); err != nil {
+		return nil, err
+	}
+
+	if err := api.ConfigureCredentialSecretPath(conf.SecretPathPrefix, conf.CredentialPathTemplate); err != nil {
+		return nil, err
+	}
+	v := &VaultCredSync{
+		log:             log,
+		conf:            conf,
+		DryRun:          conf.DryRun,
+		ForceResync:     conf.ForceResync,
+		credentialTypes: newCredentialTypeRegistry(conf.GenericRequiredKeys),
+		startTime:       time.Now(),
+		shutdown:        newShutdownState(),
+	}
+	if conf.VaultCacheSize > 0 {
+		v.credentialCache = client.NewCredentialCache(conf.VaultCacheSize, conf.VaultCacheTTL)
+	}
+	switch conf.SyncMode {
+	case syncModeCron, "":
+	case syncModeWatch:
+		if conf.SyncSourceKind == syncSourceKindFile {
+			return nil, errors.New("watch sync mode is not supported for the file sync source")
+		}
+	default:
+		return nil, errors.Errorf("sync mode %s not supported", conf.SyncMode)
+	}
+	if conf.CertBackend != certBackendKV && conf.CertBackend != certBackendPKI {
+		return nil, errors.Errorf("cert backend %s not supported", conf.CertBackend)
+	}
+	if !validNestedEncoding(conf.GenericNestedEncoding) {
+		return nil, errors.Errorf("generic credential nested encoding %s not supported", conf.GenericNestedEncoding)
+	}
+	if !validKeyNormalization(conf.KeyNormalization) {
+		return nil, errors.Errorf("credential key normalization %s not supported", conf.KeyNormalization)
+	}
+	if v.keyRoutes, err = parseKeyRoutes(conf.KeyRoutes); err != nil {
+		return nil, err
+	}
+	if v.valueTransforms, err = parseValueTransforms(conf.ValueTransforms); err != nil {
+		return nil, err
+	}
+	for _, pattern := range append(append([]string{}, conf.IncludeKeys...), conf.ExcludeKeys...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.WithMessagef(err, "invalid key pattern %s", pattern)
+		}
+	}
+	if conf.SyncSourceKind == syncSourceKindFile {
+		if conf.SyncSourceDir == "" {
+			return nil, errors.New("sync source directory is required for the file sync source")
+		}
+		v.sources = map[string]CredentialSource{conf.SyncSourceDir: NewFileCredentialSource(conf.SyncSourceDir)}
+	}
+	return v, nil
+}
+
+// RegisterCredentialType registers the validator enforced on the generic credentials of the type
+func (v *VaultCredSync) RegisterCredentialType(credentialType string, validator CredentialValidator) {
+	v.credentialTypes.register(credentialType, validator)
 }
 
 func (v *VaultCredSync) CronSpec() string {
 	return v.frequency
 }
 
+// SyncRunTimeout returns the configured maximum duration of a sync run, 0 when unbounded
+func (v *VaultCredSync) SyncRunTimeout() time.Duration {
+	return v.conf.SyncRunTimeout
+}
+
 func (v *VaultCredSync) Run() {
-	v.log.Debug("started vault credential sync job")
+	if !v.waitJitter() {
+		return
+	}
+	v.run(newRunContext(context.Background()))
+}
 
-	k8s, err := client.NewK8SClient(v.log)
+func (v *VaultCredSync) run(ctx context.Context) {
+	log := v.logger(ctx)
+	log.Debug("started vault credential sync job")
+	result, err := v.runWithResult(ctx)
 	if err != nil {
-		v.log.Errorf("failed to init k8s client, %s", err)
+		if errors.Is(err, ErrVaultSealed) {
+			log.Warn("vault is sealed, skipped vault credential sync")
+			return
+		}
+		log.Errorf("vault credential sync job failed, %s, %s", result, err)
 		return
 	}
+	log.Infof("vault credential sync job completed, %s", result)
+}
+
+// RunE performs the credential sync and returns the result of the run with an aggregated
+// error of the client init failures and every credential that failed to sync.
+func (v *VaultCredSync) RunE() (SyncResult, error) {
+	return v.runWithResult(newRunContext(context.Background()))
+}
 
-	ctx := context.Background()
-	secretValues, err := k8s.GetSecret(ctx, v.conf.VaultCredSyncSecretName, v.conf.VaultSecretNameSpace)
+// newRunContext returns the context of a sync run, carrying a logger with a unique run id
+func newRunContext(ctx context.Context) context.Context {
+	return withRunLogger(ctx, newRunLogger(uuid.New().String()))
+}
+
+func (v *VaultCredSync) runE(ctx context.Context) error {
+	if !v.shutdown.begin() {
+		return ErrShuttingDown
+	}
+	defer v.shutdown.runs.Done()
+	ctx, cancel := v.shutdown.bind(ctx)
+	defer cancel()
+
+	v.runMutex.Lock()
+	defer v.runMutex.Unlock()
+
+	if v.conf.SyncRunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, v.conf.SyncRunTimeout)
+		defer cancel()
+	}
+
+	metrics.SyncRuns.Inc()
+	err := v.sync(ctx)
+	v.recordRun(err)
 	if err != nil {
-		v.log.Debugf("failed to read sync secret, %s", err)
-		return
+		return err
 	}
-	v.log.Debugf("found %d secret values to sync", len(secretValues.Data))
+	metrics.LastSyncTimestamp.SetToCurrentTime()
+	return nil
+}
 
-	if v.lastUpdatedTime != nil {
-		if v.lastUpdatedTime.Equal(secretValues.LastUpdatedTime) {
-			v.log.Debugf("no change in secret")
-			return
-		}
+func (v *VaultCredSync) sync(ctx context.Context) error {
+	log := v.logger(ctx)
+	if err := v.checkVaultSealed(ctx); err != nil {
+		return err
 	}
 
-	vc, err := client.NewVaultClientForVaultToken(v.log, v.conf)
+	sources, err := v.credentialSources(ctx)
 	if err != nil {
-		v.log.Errorf("%s", err)
-		return
+		return err
 	}
 
-	for key, secretValue := range secretValues.Data {
-		if strings.HasPrefix(key, serviceCredSecretKeyPrefix) {
-			err = v.storeServiceCredential(ctx, vc, key, secretValue)
-			if err != nil {
-				v.log.Errorf("%s", err)
+	var errs *multierror.Error
+	lastUpdatedTimes := map[string]time.Time{}
+	sourceObjects := map[string]*sourceObject{}
+	// notDueSources are the sources with a sync frequency which are not due on this run
+	notDueSources := map[string]bool{}
+	dueScheduledSources := []string{}
+	now := time.Now()
+	tasks := []syncTask{}
+	// unreadSources are the sources which failed to read for another reason than being removed,
+	// their keys keep their previous paths so a transient read failure never prunes them
+	unreadSources := []namedSource{}
+	for _, source := range sources {
+		sourceObjects[source.id] = source.object
+		data, updatedTime, err := source.source.Fetch(ctx)
+		if err != nil {
+			if errors.Is(err, client.ErrSecretNotFound) || errors.Is(err, client.ErrConfigMapNotFound) {
+				log.Debugf("failed to read sync source %s, %s", source.id, err)
 				continue
 			}
-		} else if strings.HasPrefix(key, certSecretKeyPrefix) {
-			err = v.storeCertData(ctx, vc, key, secretValue)
-			if err != nil {
-				v.log.Errorf("%s", err)
+			unreadSources = append(unreadSources, source)
+			errs = multierror.Append(errs, errors.WithMessagef(err, "failed to read sync source %s", source.id))
+			continue
+		}
+
+		if sourcePaused(source.source) {
+			// a paused source is handled as a source not due, so its credentials are kept and not pruned
+			log.Infof("sync source %s paused by the %s annotation, skipping it", source.id, syncPausedAnnotation)
+			notDueSources[source.id] = true
+		} else if schedule := sourceSyncSchedule(log, source.id, data); schedule != nil {
+			if v.forceResync(ctx) || sourceChanged(ctx) || v.sourceDue(source.id, schedule, now) {
+				dueScheduledSources = append(dueScheduledSources, source.id)
+			} else {
+				log.Debugf("sync source %s not due as per its sync frequency", source.id)
+				notDueSources[source.id] = true
+			}
+		}
+
+		log.Debugf("found %d secret values to sync in %s", len(data), source.id)
+		lastUpdatedTimes[source.id] = updatedTime
+		recordSourceUpdated(ctx, source.id, updatedTime)
+		task := syncTask{source: source.id, namespace: source.namespace, secretName: source.secretName,
+			labels: v.propagatedLabels(source.source)}
+		for key, secretValue := range data {
+			if !v.credentialTypeEnabled(key) {
+				log.Debugf("secret key %s filtered as its credential type is not enabled", key)
 				continue
 			}
-		} else if strings.HasPrefix(key, genericSecretKeyPrefix) {
-			err = v.storeGenericCredential(ctx, vc, key, secretValue)
-			if err != nil {
-				v.log.Errorf("%s", err)
+			if selected, reason := v.keySelected(key); !selected {
+				log.Debugf("secret key %s filtered as it %s", key, reason)
 				continue
 			}
-		} else {
-			v.log.Infof("credentail type %s not supported", key)
+			task.key, task.value = key, secretValue
+			tasks = append(tasks, task)
+		}
+	}
+
+	if len(tasks) == 0 {
+		return errs.ErrorOrNil()
+	}
+
+	v.stateMutex.Lock()
+	previousUpdatedTimes := v.lastUpdatedTimes
+	previousSyncedPaths := v.syncedPaths
+	resetGeneration := v.resetGeneration
+	v.stateMutex.Unlock()
+
+	changedTasks := []syncTask{}
+	forceResync := v.forceResync(ctx)
+	for _, task := range tasks {
+		previousTime, found := previousUpdatedTimes[task.source]
+		if notDueSources[task.source] {
+			if v.conf.MergeByEntity {
+				// the keys are still merged so the entities they are part of are not written without them
+				task.frozen = true
+				changedTasks = append(changedTasks, task)
+				continue
+			}
+			recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+			continue
+		}
+		// the merged credentials are rebuilt from the keys of every source on each run
+		if forceResync || v.conf.MergeByEntity || sourceChanged(ctx) || !found || !previousTime.Equal(lastUpdatedTimes[task.source]) ||
+			task.rotatesPassword() {
+			changedTasks = append(changedTasks, task)
+			continue
+		}
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+	}
+	v.recordSourceSyncTimes(dueScheduledSources, now, resetGeneration)
+
+	secretsRemoved := false
+	for source := range previousUpdatedTimes {
+		if _, found := lastUpdatedTimes[source]; !found && !sourcesCoverSourceID(unreadSources, source) {
+			secretsRemoved = true
+		}
+	}
+	if len(changedTasks) == 0 && !secretsRemoved {
+		log.Debugf("no change in secret")
+		return errs.ErrorOrNil()
+	}
+
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		return multierror.Append(errs, err)
+	}
+	if len(v.conf.VaultFallbackAddresses) != 0 {
+		vc.SetWriteObserver(func(address string, err error) {
+			recordClusterOutcome(ctx, address, err)
+		})
+	}
+
+	syncedPaths, failedSources, err := v.syncSecretValues(ctx, vc, changedTasks)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	v.recordSyncEvents(ctx, changedTasks, sourceObjects, failedSources)
+
+	// keys of unchanged secrets, keys which failed to sync and keys of the sources which failed to
+	// read keep their previous paths, so a skipped or failed key is never treated as a removal
+	taskIDs, syncedTaskIDs := map[string]bool{}, map[string]bool{}
+	for _, task := range tasks {
+		taskIDs[task.id()] = true
+	}
+	for id := range syncedPaths {
+		syncedTaskIDs[entryTaskID(id)] = true
+	}
+	for id, secretPath := range previousSyncedPaths {
+		taskID := entryTaskID(id)
+		if (taskIDs[taskID] && !syncedTaskIDs[taskID]) || sourcesOwnTaskID(unreadSources, taskID) {
+			syncedPaths[id] = secretPath
+		}
+	}
+	if v.conf.PruneOrphans {
+		if err := v.pruneOrphanCredentials(ctx, vc, previousSyncedPaths, syncedPaths); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	if v.DryRun {
+		return errs.ErrorOrNil()
+	}
+
+	// failed secrets are not recorded, so they are synced again on the next run
+	updatedTimes := map[string]time.Time{}
+	for source, updatedTime := range lastUpdatedTimes {
+		if notDueSources[source] {
+			// a source not due keeps its previous updated time, so its changes are synced when due
+			if previousTime, found := previousUpdatedTimes[source]; found {
+				updatedTimes[source] = previousTime
+			}
+			continue
+		}
+		if len(failedSources[source]) == 0 {
+			updatedTimes[source] = updatedTime
+		}
+	}
+	for source, previousTime := range previousUpdatedTimes {
+		// an unread source keeps its previous updated time, so its removal is still detected
+		if sourcesCoverSourceID(unreadSources, source) {
+			updatedTimes[source] = previousTime
 		}
 	}
 
-	updateTime := secretValues.LastUpdatedTime.Add(0)
-	v.lastUpdatedTime = &updateTime
-	v.log.Debug("vault credential sync job completed")
+	v.stateMutex.Lock()
+	v.syncedPaths = syncedPaths
+	if v.resetGeneration == resetGeneration {
+		// a reset during the run is kept, so the next run is a full sync
+		v.lastUpdatedTimes = updatedTimes
+	}
+	v.stateMutex.Unlock()
+	return errs.ErrorOrNil()
 }
 
-func (v *VaultCredSync) storeServiceCredential(ctx context.Context, vc *client.VaultClient, secretIdentifier, secretData string) error {
+// checkVaultSealed returns ErrVaultSealed when vault is sealed, the seal status
+// is read without authentication so it works with any auth method
+func (v *VaultCredSync) checkVaultSealed(ctx context.Context) error {
+	if len(v.conf.VaultFallbackAddresses) != 0 {
+		// sealed clusters are skipped by the failover to the fallback clusters
+		return nil
+	}
+	vc, err := client.NewVaultClient(v.logger(ctx), v.conf)
+	if err != nil {
+		return errors.WithMessage(err, "failed to init vault client")
+	}
+	sealed, err := vc.IsVaultSealed()
+	if err != nil {
+		return errors.WithMessage(err, "failed to read vault seal status")
+	}
+	if sealed {
+		metrics.VaultSealed.Set(1)
+		return ErrVaultSealed
+	}
+	metrics.VaultSealed.Set(0)
+	return nil
+}
+
+// multiSecret reports whether the sync secrets are selected by label selector or name prefix,
+// instead of the single sync secret name
+func (v *VaultCredSync) multiSecret() bool {
+	return v.conf.SyncSecretSelector != "" || v.conf.SyncSecretPrefix != ""
+}
+
+// syncSecretNamespaces returns the namespaces to read the sync secret from, and whether
+// multiple namespaces are configured, in which case vault paths are prefixed with the namespace.
+func (v *VaultCredSync) syncSecretNamespaces(ctx context.Context, k8s *client.K8SClient) ([]string, bool, error) {
+	if len(v.conf.SyncNamespaces) == 0 && v.conf.SyncNamespaceSelector == "" {
+		return []string{v.conf.VaultSecretNameSpace}, false, nil
+	}
+
+	namespaces := append([]string{}, v.conf.SyncNamespaces...)
+	if v.conf.SyncNamespaceSelector != "" {
+		selectedNamespaces, err := k8s.ListNamespaces(ctx, v.conf.SyncNamespaceSelector)
+		if err != nil {
+			return nil, false, errors.WithMessage(err, "failed to list sync secret namespaces")
+		}
+		for _, namespace := range selectedNamespaces {
+			found := false
+			for _, existingNamespace := range namespaces {
+				if existingNamespace == namespace {
+					found = true
+					break
+				}
+			}
+			if !found {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+	}
+	return namespaces, true, nil
+}
+
+type syncTask struct {
+	key   string
+	value string
+	// namespace of the sync secret, set only when syncing multiple namespaces
+	namespace string
+	// secretName of the sync secret, set only when syncing the secrets matching a selector or prefix
+	secretName string
+	// source is the namespace/name of the sync secret
+	source string
+	// labels of the sync secret propagated to the credential metadata
+	labels map[string]string
+	// entry of the credential in a secret value holding a list of credentials, e.g. "[1]"
+	entry string
+	// frozen is set for the keys of paused or not due sources in the merge by entity mode,
+	// the merged credentials they are part of are kept unchanged
+	frozen bool
+}
+
+// id identifies the secret key across all the synced namespaces and secrets
+func (t syncTask) id() string {
+	id := t.key + t.entry
+	if t.secretName != "" {
+		id = t.secretName + "/" + id
+	}
+	if t.namespace != "" {
+		id = t.namespace + "/" + id
+	}
+	return id
+}
+
+// rotatesPassword reports whether the task is a service credential with password rotation,
+// which is synced on every run so the rotation interval is checked
+func (t syncTask) rotatesPassword() bool {
+	if !strings.HasPrefix(t.key, serviceCredSecretKeyPrefix) {
+		return false
+	}
 	var serviceCredData ServiceCredentail
-	err := json.Unmarshal([]byte(secretData), &serviceCredData)
+	return json.Unmarshal([]byte(t.value), &serviceCredData) == nil && serviceCredData.Rotate
+}
+
+// syncSecretValues dispatches every secret key to a bounded pool of workers
+// and returns the vault paths written per key, the errors of the failed keys
+// by sync secret, along with the aggregated errors of all failed keys.
+func (v *VaultCredSync) syncSecretValues(ctx context.Context, vc *client.VaultClient,
+	syncTasks []syncTask) (map[string]vaultPath, map[string][]error, error) {
+	concurrency := v.conf.SyncConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mutex sync.Mutex
+		errs  *multierror.Error
+	)
+	syncedPaths := map[string]vaultPath{}
+	failedSources := map[string][]error{}
+	// failedTaskIDs are the keys which failed or were not synced, the merged credentials they are part of are not written
+	failedTaskIDs := map[string]bool{}
+	tasks := make(chan syncTask)
+	// abort is closed once the errors of the run exceed the configured maximum
+	abort := make(chan struct{})
+	errorCount := 0
+	var merger *entityMerger
+	if v.conf.MergeByEntity {
+		ctx, merger = withEntityMerger(ctx)
+	}
+	ctx = v.withCredentialVariables(ctx)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				secretPaths, err := v.storeCredential(ctx, vc, task)
+				mutex.Lock()
+				if err != nil {
+					metrics.SyncErrors.Inc(credentialTypeLabel(task.key))
+					recordFailure(ctx, task, err)
+					errs = multierror.Append(errs, err)
+					failedSources[task.source] = append(failedSources[task.source], err)
+					failedTaskIDs[task.id()] = true
+					errorCount++
+					if v.conf.MaxErrorsBeforeAbort > 0 && errorCount == v.conf.MaxErrorsBeforeAbort+1 {
+						close(abort)
+					}
+				} else {
+					for id, secretPath := range secretPaths {
+						syncedPaths[id] = secretPath
+					}
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	// abortDispatch marks the sources of the undispatched keys failed once the run is aborted
+	abortDispatch := func(pendingTasks []syncTask) {
+		reason := fmt.Sprintf("aborted after more than %d errors, %d secret keys not synced",
+			v.conf.MaxErrorsBeforeAbort, len(pendingTasks))
+		v.logger(ctx).Errorf("credential sync %s", reason)
+		recordAborted(ctx, reason)
+		mutex.Lock()
+		for _, pendingTask := range pendingTasks {
+			failedSources[pendingTask.source] = append(failedSources[pendingTask.source],
+				errors.WithMessagef(ErrTooManyErrors, "%s not synced", pendingTask.id()))
+			failedTaskIDs[pendingTask.id()] = true
+		}
+		errs = multierror.Append(errs, errors.WithMessage(ErrTooManyErrors, reason))
+		mutex.Unlock()
+	}
+
+dispatch:
+	for i, task := range syncTasks {
+		select {
+		case <-abort:
+			abortDispatch(syncTasks[i:])
+			break dispatch
+		default:
+		}
+
+		select {
+		case tasks <- task:
+		case <-v.shutdown.draining:
+			// the sources of the undispatched keys are marked failed to keep their previous state
+			pendingTasks := syncTasks[i:]
+			v.shutdown.recordPending(len(pendingTasks))
+			mutex.Lock()
+			for _, pendingTask := range pendingTasks {
+				failedSources[pendingTask.source] = append(failedSources[pendingTask.source],
+					errors.WithMessagef(ErrShuttingDown, "%s not synced", pendingTask.id()))
+				failedTaskIDs[pendingTask.id()] = true
+			}
+			errs = multierror.Append(errs, errors.WithMessagef(ErrShuttingDown, "%d secret keys not synced", len(pendingTasks)))
+			mutex.Unlock()
+			break dispatch
+		case <-abort:
+			abortDispatch(syncTasks[i:])
+			break dispatch
+		}
+	}
+	close(tasks)
+	wg.Wait()
+	if merger != nil {
+		errs = v.syncMergedCredentials(ctx, vc, merger, syncTasks, failedTaskIDs, failedSources, errs)
+	}
+	return syncedPaths, failedSources, errs.ErrorOrNil()
+}
+
+// pruneOrphanCredentials deletes the vault paths written on the previous run that
+// are no longer produced by any key of the sync secrets.
+func (v *VaultCredSync) pruneOrphanCredentials(ctx context.Context, vc *client.VaultClient,
+	previousSyncedPaths, syncedPaths map[string]vaultPath) error {
+	log := v.logger(ctx)
+	activePaths := map[vaultPath]bool{}
+	for _, secretPath := range syncedPaths {
+		activePaths[secretPath] = true
+	}
+
+	var errs *multierror.Error
+	for key, secretPath := range previousSyncedPaths {
+		if activePaths[secretPath] {
+			continue
+		}
+		if owner, owned := v.credentialOwned(ctx, vc, secretPath); !owned {
+			log.Infof("orphan credential %s of removed secret key %s is owned by %s, not pruned", secretPath, key, owner)
+			continue
+		}
+		if v.DryRun {
+			log.Infof("dry run, orphan credential %s of removed secret key %s would be pruned", secretPath, key)
+			continue
+		}
+		err := v.vaultOp(ctx, "delete", func(ctx context.Context) error {
+			return vc.DeleteCredential(ctx, secretPath.mount, secretPath.path)
+		})
+		if err != nil && !errors.Is(err, client.ErrCredentialNotFound) {
+			// keep tracking the path so that the delete is retried on the next run
+			syncedPaths[key] = secretPath
+			errs = multierror.Append(errs, errors.WithMessagef(err, "failed to prune %s orphan credential", key))
+			continue
+		}
+		activePaths[secretPath] = true
+		log.Infof("pruned orphan credential %s of removed secret key %s", secretPath, key)
+	}
+	return errs.ErrorOrNil()
+}
+
+// credentialTypeEnabled reports whether the secret key prefix is in the enabled credential types,
+// all the types are enabled when none are configured
+func (v *VaultCredSync) credentialTypeEnabled(key string) bool {
+	if len(v.conf.EnabledCredentialTypes) == 0 {
+		return true
+	}
+	for _, credentialType := range v.conf.EnabledCredentialTypes {
+		if strings.HasPrefix(key, credentialType) {
+			return true
+		}
+	}
+	return false
+}
+
+// keySelected reports whether the secret key matches an include pattern, when configured, and no
+// exclude pattern. The patterns are globs, e.g. SERVICE-CRED-db-*. It returns why a key is not selected.
+func (v *VaultCredSync) keySelected(key string) (bool, string) {
+	if len(v.conf.IncludeKeys) != 0 {
+		included := false
+		for _, pattern := range v.conf.IncludeKeys {
+			if matched, _ := path.Match(pattern, key); matched {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false, "matches no include pattern"
+		}
+	}
+	for _, pattern := range v.conf.ExcludeKeys {
+		if matched, _ := path.Match(pattern, key); matched {
+			return false, "matches exclude pattern " + pattern
+		}
+	}
+	return true, ""
+}
+
+// credentialTypeLabel returns the metric label of the credential type derived from the secret key prefix.
+func credentialTypeLabel(key string) string {
+	if strings.HasPrefix(key, serviceCredSecretKeyPrefix) {
+		return "service"
+	} else if strings.HasPrefix(key, certSecretKeyPrefix) {
+		return "cert"
+	} else if strings.HasPrefix(key, genericSecretKeyPrefix) {
+		return "generic"
+	}
+	return "unknown"
+}
+
+// storeCredential stores the credentials of the secret key, returning the vault paths written keyed by the task id
+func (v *VaultCredSync) storeCredential(ctx context.Context, vc *client.VaultClient, task syncTask) (map[string]vaultPath, error) {
+	log := v.logger(ctx)
+	if strings.HasPrefix(task.value, transitCiphertextPrefix) {
+		if v.conf.TransitKeyName == "" {
+			return nil, errors.Errorf("%s secret data is transit encrypted but no transit key is configured", task.id())
+		}
+		err := v.vaultOp(ctx, "transit decrypt", func(ctx context.Context) (err error) {
+			task.value, err = vc.TransitDecrypt(ctx, v.conf.TransitKeyName, task.value)
+			return
+		})
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to decrypt %s secret data", task.id())
+		}
+	}
+
+	if strings.HasPrefix(task.key, serviceCredSecretKeyPrefix) {
+		return v.storeServiceCredentials(ctx, vc, task)
+	} else if strings.HasPrefix(task.key, certSecretKeyPrefix) {
+		secretPath, err := v.storeCertData(ctx, vc, task)
+		return storedPath(task, secretPath, err)
+	} else if strings.HasPrefix(task.key, genericSecretKeyPrefix) {
+		secretPath, err := v.storeGenericCredential(ctx, vc, task)
+		return storedPath(task, secretPath, err)
+	}
+	log.Infof("credentail type %s not supported", task.id())
+	return nil, nil
+}
+
+// storedPath keys the vault path written for a single credential by the task id
+func storedPath(task syncTask, secretPath vaultPath, err error) (map[string]vaultPath, error) {
+	if err != nil || secretPath == (vaultPath{}) {
+		return nil, err
+	}
+	return map[string]vaultPath{task.id(): secretPath}, nil
+}
+
+// entryTaskID returns the id of the secret key of a credential list entry id
+func entryTaskID(id string) string {
+	if strings.HasSuffix(id, "]") {
+		if i := strings.LastIndex(id, "["); i > 0 {
+			return id[:i]
+		}
+	}
+	return id
+}
+
+// storeServiceCredentials stores a service credential, or each service credential of a JSON list,
+// the list entries are stored to their own paths and are identified by their index
+func (v *VaultCredSync) storeServiceCredentials(ctx context.Context, vc *client.VaultClient, task syncTask) (map[string]vaultPath, error) {
+	if !strings.HasPrefix(strings.TrimSpace(task.value), "[") {
+		secretPath, err := v.storeServiceCredential(ctx, vc, task)
+		return storedPath(task, secretPath, err)
+	}
+	var entries []json.RawMessage
+	if err := json.Unmarshal([]byte(task.value), &entries); err != nil {
+		return nil, newParseError(task.id(), credentialTypeLabel(task.key), err)
+	}
+	var errs *multierror.Error
+	secretPaths := map[string]vaultPath{}
+	for i, entry := range entries {
+		entryTask := task
+		entryTask.entry, entryTask.value = fmt.Sprintf("[%d]", i), string(entry)
+		secretPath, err := v.storeServiceCredential(ctx, vc, entryTask)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		if secretPath != (vaultPath{}) {
+			secretPaths[entryTask.id()] = secretPath
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return secretPaths, nil
+}
+
+func (v *VaultCredSync) storeServiceCredential(ctx context.Context, vc *client.VaultClient, task syncTask) (vaultPath, error) {
+	log := v.logger(ctx)
+	secretIdentifier := task.id()
+	var serviceCredData ServiceCredentail
+	err := json.Unmarshal([]byte(task.value), &serviceCredData)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to parse %s secret data", secretIdentifier)
+		return vaultPath{}, newParseError(secretIdentifier, credentialTypeLabel(task.key), err)
+	}
+	serviceCredData.AdditionalData, err = v.serviceAdditionalData(ctx, secretIdentifier, serviceCredData.AdditionalData)
+	if err != nil {
+		return vaultPath{}, err
 	}
 
-	if len(serviceCredData.UserName) == 0 || len(serviceCredData.Password) == 0 || len(serviceCredData.EntityName) == 0 {
-		return errors.WithMessagef(err, "credential attributes are emty for %s secret data", secretIdentifier)
+	if len(serviceCredData.CredIndentifier) == 0 && v.conf.AutoGenerateIdentifier {
+		identifierData := map[string]string{serviceCredentialUserNameKey: serviceCredData.UserName,
+			serviceCredentialPasswordKey: serviceCredData.Password}
+		for key, val := range serviceCredData.AdditionalData {
+			identifierData[key] = val
+		}
+		serviceCredData.CredIndentifier = v.generateIdentifier(serviceCredData.EntityName, identifierData)
+	}
+
+	requiredFields := map[string]string{"userName": serviceCredData.UserName, "entityName": serviceCredData.EntityName}
+	if !serviceCredData.Rotate {
+		requiredFields["password"] = serviceCredData.Password
+	}
+	if err := newValidationError(secretIdentifier, credentialTypeLabel(task.key), requiredFields); err != nil {
+		return vaultPath{}, err
 	}
 
 	cred := map[string]string{serviceCredentialUserNameKey: serviceCredData.UserName,
@@ -143,63 +872,494 @@ func (v *VaultCredSync) storeServiceCredential(ctx context.Context, vc *client.V
 	for key, val := range serviceCredData.AdditionalData {
 		cred[key] = val
 	}
+	v.transformCredentialValues(cred)
 
-	secretPath := api.PrepareCredentialSecretPath(strings.ToLower(serviceCredSecretKeyPrefix), serviceCredData.EntityName, serviceCredData.CredIndentifier)
-	err = vc.PutCredential(ctx, api.CredentialMountPath(), secretPath, cred)
+	secretPath, err := v.credentialVaultPath(task, strings.ToLower(serviceCredSecretKeyPrefix), serviceCredData.EntityName, serviceCredData.CredIndentifier)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to write %s secret data to vault", secretIdentifier)
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential path for %s secret data", secretIdentifier)
 	}
-	v.log.Infof("stored sync service credential for %s/%s", serviceCredData.EntityName, serviceCredData.CredIndentifier)
-	return nil
+	if serviceCredData.Rotate {
+		password, previousPassword, rotatedAt, err := v.rotatedServicePassword(ctx, vc, secretPath, serviceCredData.RotationInterval)
+		if err != nil {
+			return vaultPath{}, errors.WithMessagef(err, "password rotation failed for %s secret data", secretIdentifier)
+		}
+		cred[serviceCredentialPasswordKey] = password
+		cred[serviceCredentialRotatedAtKey] = rotatedAt
+		if previousPassword != "" {
+			cred[serviceCredentialPreviousPasswordKey] = previousPassword
+		}
+	}
+	written, err := v.putCredential(ctx, vc, task, secretPath, cred)
+	if err != nil || !written {
+		return secretPath, err
+	}
+	log.Infof("stored sync service credential for %s/%s", serviceCredData.EntityName, serviceCredData.CredIndentifier)
+	return secretPath, nil
+}
+
+// serviceAdditionalData drops the additional data keys shadowing the userName and password
+// of a service credential so the primary fields always win, in strict mode they are rejected
+func (v *VaultCredSync) serviceAdditionalData(ctx context.Context, secretIdentifier string, additionalData map[string]string) (map[string]string, error) {
+	shadowedKeys := []string{}
+	for _, key := range []string{serviceCredentialUserNameKey, serviceCredentialPasswordKey} {
+		if _, found := additionalData[key]; found {
+			shadowedKeys = append(shadowedKeys, key)
+		}
+	}
+	if len(shadowedKeys) == 0 {
+		return additionalData, nil
+	}
+	if v.conf.StrictAdditionalData {
+		return nil, errors.Errorf("additional data keys %s of %s secret data collide with the service credential fields",
+			strings.Join(shadowedKeys, ", "), secretIdentifier)
+	}
+	v.logger(ctx).Warn(fmt.Sprintf("ignoring additional data keys %s of %s secret data, they collide with the service credential fields",
+		strings.Join(shadowedKeys, ", "), secretIdentifier))
+	filtered := make(map[string]string, len(additionalData))
+	for key, val := range additionalData {
+		filtered[key] = val
+	}
+	for _, key := range shadowedKeys {
+		delete(filtered, key)
+	}
+	return filtered, nil
 }
 
-func (v *VaultCredSync) storeCertData(ctx context.Context, vc *client.VaultClient, secretIdentifier, secretData string) error {
+func (v *VaultCredSync) storeCertData(ctx context.Context, vc *client.VaultClient, task syncTask) (vaultPath, error) {
+	log := v.logger(ctx)
+	secretIdentifier := task.id()
 	var certData CertificateData
-	err := json.Unmarshal([]byte(secretData), &certData)
+	err := json.Unmarshal([]byte(task.value), &certData)
+	if err != nil {
+		return vaultPath{}, newParseError(secretIdentifier, credentialTypeLabel(task.key), err)
+	}
+
+	if len(certData.CertIndentifier) == 0 && v.conf.AutoGenerateIdentifier {
+		certData.CertIndentifier = v.generateIdentifier(certData.EntityName,
+			map[string]string{caDataKey: certData.CACert, certDataKey: certData.Cert, keyDataKey: certData.Key})
+	}
+
+	err = newValidationError(secretIdentifier, credentialTypeLabel(task.key), map[string]string{"caCert": certData.CACert,
+		"cert": certData.Cert, "key": certData.Key, "entityName": certData.EntityName, "certIdentifier": certData.CertIndentifier})
+	if err != nil {
+		return vaultPath{}, err
+	}
+
+	if err := decodeCertData(&certData); err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "failed to decode %s secret data", secretIdentifier)
+	}
+
+	leafCert, err := validateCertData(certData.CACert, certData.Cert, certData.Key)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to parse %s secret data", secretIdentifier)
+		return vaultPath{}, errors.WithMessagef(err, "certificate validation failed for %s secret data", secretIdentifier)
 	}
 
-	if len(certData.CACert) == 0 || len(certData.Cert) == 0 || len(certData.Key) == 0 ||
-		len(certData.EntityName) == 0 || len(certData.CertIndentifier) == 0 {
-		return errors.WithMessagef(err, "credential attributes are emty for %s secret data", secretIdentifier)
+	if time.Until(leafCert.NotAfter) < v.conf.CertExpiryWarningThreshold {
+		metrics.CertExpiring.Inc()
+		log.Warn(fmt.Sprintf("certificate for %s/%s expires at %s",
+			certData.EntityName, certData.CertIndentifier, leafCert.NotAfter.Format(time.RFC3339)))
+	}
+
+	if v.conf.CertBackend == certBackendPKI {
+		if _, diffing := diffCollectorFrom(ctx); diffing {
+			return vaultPath{}, nil
+		}
+		// the pki mount is not a credential path, so it is not tracked for pruning
+		return vaultPath{}, v.storePKICertificate(ctx, vc, task, certData)
 	}
 
 	cred := map[string]string{caDataKey: certData.CACert,
 		certDataKey: certData.Cert,
 		keyDataKey:  certData.Key}
+	if v.conf.CertFullChain {
+		fullChain, err := buildFullChain(certData.CACert, certData.Cert)
+		if err != nil {
+			return vaultPath{}, errors.WithMessagef(err, "failed to build the full chain for %s secret data", secretIdentifier)
+		}
+		cred[fullChainDataKey] = fullChain
+	}
+	v.transformCredentialValues(cred)
 
-	secretPath := api.PrepareCredentialSecretPath(strings.ToLower(certSecretKeyPrefix), certData.EntityName, certData.CertIndentifier)
-	err = vc.PutCredential(ctx, api.CredentialMountPath(), secretPath, cred)
+	secretPath, err := v.credentialVaultPath(task, strings.ToLower(certSecretKeyPrefix), certData.EntityName, certData.CertIndentifier)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to write %s secret data to vault", secretIdentifier)
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential path for %s secret data", secretIdentifier)
 	}
-	v.log.Infof("stored sync cert for %s/%s", certData.EntityName, certData.CertIndentifier)
-	return nil
+	written, err := v.putCredential(ctx, vc, task, secretPath, cred)
+	if err != nil || !written {
+		return secretPath, err
+	}
+	log.Infof("stored sync cert for %s/%s", certData.EntityName, certData.CertIndentifier)
+	return secretPath, nil
 }
 
-func (v *VaultCredSync) storeGenericCredential(ctx context.Context, vc *client.VaultClient, secretIdentifier, secretData string) error {
+func (v *VaultCredSync) storeGenericCredential(ctx context.Context, vc *client.VaultClient, task syncTask) (vaultPath, error) {
+	log := v.logger(ctx)
+	secretIdentifier := task.id()
 	var genericCredData GenericCredential
-	err := json.Unmarshal([]byte(secretData), &genericCredData)
+	err := json.Unmarshal([]byte(task.value), &genericCredData)
 	if err != nil {
-		return errors.WithMessagef(err, "failed to parse %s secret data", secretIdentifier)
+		return vaultPath{}, newParseError(secretIdentifier, credentialTypeLabel(task.key), err)
 	}
 
-	if len(genericCredData.EntityName) == 0 || len(genericCredData.CredIndentifier) == 0 || len(genericCredData.CredentialType) == 0 {
-		return errors.WithMessagef(err, "credential attributes are emty for %s secret data", secretIdentifier)
+	cred, err := flattenCredential(genericCredData.Credential, v.conf.GenericNestedEncoding)
+	if err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential data for %s secret data", secretIdentifier)
 	}
 
-	cred := map[string]string{}
-	for key, val := range genericCredData.Credential {
-		cred[key] = val
+	cred, err = normalizeCredentialKeys(log, cred, v.conf.KeyNormalization)
+	if err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential data for %s secret data", secretIdentifier)
 	}
 
-	secretPath := api.PrepareCredentialSecretPath(genericCredData.CredentialType, genericCredData.EntityName, genericCredData.CredIndentifier)
-	err = vc.PutCredential(ctx, api.CredentialMountPath(), secretPath, cred)
+	if err := v.expandCredentialVariables(v.credentialVariablesFrom(ctx), cred); err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential data for %s secret data", secretIdentifier)
+	}
+	if err := v.resolveCredentialReferences(ctx, vc, cred); err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential data for %s secret data", secretIdentifier)
+	}
+	v.transformCredentialValues(cred)
+
+	if len(genericCredData.EntityName) == 0 {
+		// the entity name stays required unless a default entity name is configured
+		genericCredData.EntityName = v.conf.DefaultEntityName
+	}
+
+	if len(genericCredData.CredIndentifier) == 0 && v.conf.AutoGenerateIdentifier {
+		genericCredData.CredIndentifier = v.generateIdentifier(genericCredData.EntityName, cred)
+	}
+
+	err = newValidationError(secretIdentifier, credentialTypeLabel(task.key), map[string]string{"entityName": genericCredData.EntityName,
+		"credIdentifier": genericCredData.CredIndentifier, "credentialType": genericCredData.CredentialType})
 	if err != nil {
-		return errors.WithMessagef(err, "failed to write %s secret data to vault", secretIdentifier)
+		return vaultPath{}, err
 	}
-	v.log.Infof("stored sync credential for %s/%s/%s", genericCredData.CredentialType, genericCredData.EntityName, genericCredData.CredIndentifier)
+
+	err = v.credentialTypes.validate(genericCredData.CredentialType, cred, v.conf.StrictGenericValidation)
+	if err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "credential validation failed for %s secret data", secretIdentifier)
+	}
+
+	if genericCredData.Binary {
+		if err := markBinaryCredential(cred); err != nil {
+			return vaultPath{}, errors.WithMessagef(err, "invalid binary credential for %s secret data", secretIdentifier)
+		}
+	}
+
+	secretPath, err := v.credentialVaultPath(task, genericCredData.CredentialType, genericCredData.EntityName, genericCredData.CredIndentifier)
+	if err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid credential path for %s secret data", secretIdentifier)
+	}
+	ttl, err := v.genericCredentialTTL(genericCredData)
+	if err != nil {
+		return vaultPath{}, errors.WithMessagef(err, "invalid ttl for %s secret data", secretIdentifier)
+	}
+	written, err := v.putCredential(ctx, vc, task, secretPath, cred)
+	if err != nil || !written {
+		return secretPath, err
+	}
+	if ttl > 0 {
+		err := v.vaultOp(ctx, "metadata write", func(ctx context.Context) error {
+			return vc.ConfigureDeleteVersionAfter(ctx, secretPath.mount, secretPath.path, ttl)
+		})
+		if err != nil {
+			return secretPath, errors.WithMessagef(err, "failed to configure ttl of %s secret data", secretIdentifier)
+		}
+	}
+	log.Infof("stored sync credential for %s/%s/%s", genericCredData.CredentialType, genericCredData.EntityName, genericCredData.CredIndentifier)
+	return secretPath, nil
+
+}
+
+// markBinaryCredential marks the credential values as base64 encoded binary data. Vault kv stores
+// strings, so the values are stored base64 encoded as given, decoding them only checks they are valid.
+func markBinaryCredential(cred map[string]string) error {
+	for key, val := range cred {
+		if _, err := base64.StdEncoding.DecodeString(val); err != nil {
+			return errors.WithMessagef(err, "credential %s is not valid base64", key)
+		}
+	}
+	cred[credentialEncodingKey] = certEncodingBase64
 	return nil
+}
+
+// genericCredentialTTL returns the ttl of the generic credential, else the ttl configured for its
+// credential type, 0 when none is set
+func (v *VaultCredSync) genericCredentialTTL(genericCredData GenericCredential) (time.Duration, error) {
+	ttlValue := genericCredData.TTL
+	if ttlValue == "" {
+		ttlValue = v.conf.CredentialTTLs[genericCredData.CredentialType]
+	}
+	if ttlValue == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(ttlValue)
+	if err != nil {
+		return 0, err
+	}
+	if ttl < 0 {
+		return 0, errors.Errorf("ttl %s is negative", ttlValue)
+	}
+	return ttl, nil
+}
+
+// vaultPath locates a credential in vault by its mount and secret path
+type vaultPath struct {
+	mount string
+	path  string
+}
 
+func (p vaultPath) String() string {
+	return p.mount + "/" + p.path
+}
+
+// credentialVaultPath resolves the vault mount routed for the secret key or configured for the
+// credential type and prepares the secret path, prefixed with the path prefix of the key route
+// and with the namespace of the sync secret when syncing multiple namespaces.
+func (v *VaultCredSync) credentialVaultPath(task syncTask, credentialType, credEntityName, credIdentifier string) (vaultPath, error) {
+	if err := api.ValidateCredentialPathParts(credentialType, credEntityName, credIdentifier); err != nil {
+		return vaultPath{}, err
+	}
+
+	if v.conf.MergeByEntity {
+		credentialType, credIdentifier = entityCredentialType, entityCredentialIdentifier
+	}
+	mountPath, found := v.conf.CredentialMountPaths[credentialType]
+	if !found || mountPath == "" {
+		mountPath = api.CredentialMountPath()
+	}
+
+	secretPath := api.PrepareCredentialSecretPath(credentialType, credEntityName, credIdentifier)
+	if task.namespace != "" {
+		secretPath = task.namespace + "/" + secretPath
+	}
+	if route, found := v.keyRoute(task.key); found {
+		mountPath = route.mountPath
+		if route.pathPrefix != "" {
+			secretPath = route.pathPrefix + "/" + secretPath
+		}
+	}
+	return vaultPath{mount: mountPath, path: secretPath}, nil
+}
+
+// putCredential writes the credential to vault and reports whether it was written.
+// The write is skipped when vault already holds the same credential data, and
+// in dry run mode only the intended write is logged with the credential keys.
+func (v *VaultCredSync) putCredential(ctx context.Context, vc *client.VaultClient,
+	task syncTask, secretPath vaultPath, cred map[string]string) (bool, error) {
+	if m, merging := entityMergerFrom(ctx); merging {
+		m.add(task, secretPath, cred)
+		return false, nil
+	}
+	ctx, span := v.startSpan(ctx, credentialWriteSpanName, map[string]string{
+		"credential.type": credentialTypeLabel(task.key),
+		"vault.path":      secretPath.String(),
+	})
+	written, err := v.writeCredential(ctx, vc, task, secretPath, cred)
+	switch {
+	case err != nil:
+		span.SetAttribute("outcome", "failed")
+	case written:
+		span.SetAttribute("outcome", "written")
+	default:
+		span.SetAttribute("outcome", "skipped")
+	}
+	span.End(err)
+	return written, err
+}
+
+// mirrorCredential writes the credential to the mirror clusters which differ, retried separately
+// from the write to the primary cluster so a failing mirror does not repeat the primary write
+func (v *VaultCredSync) mirrorCredential(ctx context.Context, vc *client.VaultClient, secretPath vaultPath, cred map[string]string) error {
+	return retryWithBackoff(ctx, v.logger(ctx), v.conf.VaultWriteMaxAttempts, v.conf.VaultWriteMaxElapsedTime, func() error {
+		return v.vaultOp(ctx, "mirror write", func(ctx context.Context) error {
+			return vc.MirrorCredential(ctx, secretPath.mount, secretPath.path, cred)
+		})
+	})
+}
+
+func (v *VaultCredSync) writeCredential(ctx context.Context, vc *client.VaultClient,
+	task syncTask, secretPath vaultPath, cred map[string]string) (bool, error) {
+	log := v.logger(ctx)
+	secretIdentifier := task.id()
+	if err := v.checkCredentialSize(task, cred); err != nil {
+		return false, err
+	}
+	if claimedBy, claimed := claimPath(ctx, secretPath, secretIdentifier); !claimed {
+		log.Warn(fmt.Sprintf("secret keys %s and %s resolve to the same vault path %s", claimedBy, secretIdentifier, secretPath))
+		if v.conf.FailOnDuplicatePath {
+			return false, errors.Errorf("%s secret data resolves to vault path %s already written by %s",
+				secretIdentifier, secretPath, claimedBy)
+		}
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+		recordSkipReason(ctx, secretPath, "already written by "+claimedBy)
+		return false, nil
+	}
+	cred, err := v.encryptCredentialFields(ctx, vc, secretPath, cred)
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to encrypt %s secret data", secretIdentifier)
+	}
+	if d, diffing := diffCollectorFrom(ctx); diffing {
+		return false, v.recordDrift(ctx, vc, d, task, secretPath, cred)
+	}
+
+	if owner, owned := v.credentialOwned(ctx, vc, secretPath); !owned {
+		log.Warn(fmt.Sprintf("%s secret data not written, %s is owned by %s", secretIdentifier, secretPath, owner))
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+		recordSkipReason(ctx, secretPath, "owned by "+owner)
+		return false, nil
+	}
+
+	unchanged := false
+	if !v.forceResync(ctx) {
+		_ = v.vaultOp(ctx, "read", func(ctx context.Context) error {
+			unchanged = vc.IsCredentialUnchanged(ctx, secretPath.mount, secretPath.path, cred)
+			return nil
+		})
+	}
+	if unchanged {
+		if !v.DryRun {
+			// the mirror clusters may still have missed an earlier write of the credential
+			if err := v.mirrorCredential(ctx, vc, secretPath, cred); err != nil {
+				return false, errors.WithMessagef(err, "failed to mirror %s secret data", secretIdentifier)
+			}
+		}
+		metrics.SyncSkipped.Inc()
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+		recordSkipReason(ctx, secretPath, "unchanged")
+		log.Debugf("%s secret data unchanged at %s, skipping write", secretIdentifier, secretPath)
+		return false, nil
+	}
+
+	if v.DryRun {
+		keys := make([]string, 0, len(cred))
+		for key := range cred {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		log.Infof("dry run, %s secret data would be written to %s with keys %v",
+			secretIdentifier, secretPath, keys)
+		recordOutcome(ctx, credentialTypeLabel(task.key), 0, 1, 0)
+		recordSkipReason(ctx, secretPath, "dry run")
+		return false, nil
+	}
+
+	maxVersions, configureMaxVersions := v.conf.CredentialMaxVersions[credentialTypeLabel(task.key)]
+	if configureMaxVersions {
+		// max versions is only applied to new credentials, so a version count tuned in vault is kept
+		err := v.vaultOp(ctx, "metadata read", func(ctx context.Context) (err error) {
+			_, err = vc.GetCredentialMetadata(ctx, secretPath.mount, secretPath.path)
+			return
+		})
+		configureMaxVersions = errors.Is(err, client.ErrCredentialNotFound)
+	}
+
+	err = retryWithBackoff(ctx, log, v.conf.VaultWriteMaxAttempts, v.conf.VaultWriteMaxElapsedTime, func() error {
+		return v.vaultOp(ctx, "write", func(ctx context.Context) error {
+			return vc.PutPrimaryCredential(ctx, secretPath.mount, secretPath.path, cred)
+		})
+	})
+	if err != nil {
+		return false, errors.WithMessagef(err, "failed to write %s secret data to vault", secretIdentifier)
+	}
+	v.shutdown.recordWritten()
+	if err := v.mirrorCredential(ctx, vc, secretPath, cred); err != nil {
+		return false, errors.WithMessagef(err, "%s secret data written but failed to mirror", secretIdentifier)
+	}
+
+	if configureMaxVersions {
+		err := v.vaultOp(ctx, "metadata write", func(ctx context.Context) error {
+			return vc.ConfigureMaxVersions(ctx, secretPath.mount, secretPath.path, maxVersions)
+		})
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to configure max versions of %s secret data", secretIdentifier)
+		}
+	}
+
+	if v.conf.VerifyWrites {
+		var writtenCred map[string]string
+		err := v.vaultOp(ctx, "read", func(ctx context.Context) (err error) {
+			writtenCred, err = vc.GetCredential(ctx, secretPath.mount, secretPath.path)
+			return
+		})
+		if err != nil {
+			return false, errors.WithMessagef(err, "failed to read back %s secret data from vault", secretIdentifier)
+		}
+		if diff := credentialDiff(cred, writtenCred); len(diff) != 0 {
+			return false, errors.Errorf("%s secret data read back from %s does not match the written data, %s",
+				secretIdentifier, secretPath, strings.Join(diff, ", "))
+		}
+	}
+
+	err = v.vaultOp(ctx, "metadata write", func(ctx context.Context) error {
+		metadata := map[string]string{}
+		for key, val := range task.labels {
+			metadata[key] = val
+		}
+		metadata[syncedByMetadataKey] = syncedByMetadataValue
+		metadata[syncedAtMetadataKey] = time.Now().UTC().Format(time.RFC3339)
+		metadata[sourceSecretMetadataKey] = task.source
+		metadata[sourceKeyMetadataKey] = task.key
+		return vc.PutCredentialMetadata(ctx, secretPath.mount, secretPath.path, metadata)
+	})
+	if err != nil && !errors.Is(err, client.ErrMetadataNotSupported) {
+		return false, errors.WithMessagef(err, "failed to write %s audit metadata to vault", secretIdentifier)
+	}
+	recordOutcome(ctx, credentialTypeLabel(task.key), 1, 0, 0)
+	return true, nil
+}
+
+// credentialOwned reports whether the credential at the path is owned by the sync, a credential
+// without an owner is treated as owned. It returns the owner of the credentials owned by others.
+func (v *VaultCredSync) credentialOwned(ctx context.Context, vc *client.VaultClient, secretPath vaultPath) (string, bool) {
+	if v.conf.CredentialOwner == "" {
+		return "", true
+	}
+	var metadata map[string]string
+	err := v.vaultOp(ctx, "metadata read", func(ctx context.Context) (err error) {
+		metadata, err = vc.GetCredentialMetadata(ctx, secretPath.mount, secretPath.path)
+		return
+	})
+	if err != nil {
+		return "", true
+	}
+	owner := metadata[client.OwnerMetadataKey]
+	return owner, owner == "" || owner == v.conf.CredentialOwner
+}
+
+// checkCredentialSize rejects the credential when its json serialized size exceeds the configured limit
+func (v *VaultCredSync) checkCredentialSize(task syncTask, cred map[string]string) error {
+	if v.conf.MaxCredentialBytes <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return errors.WithMessagef(err, "failed to serialize %s secret data", task.id())
+	}
+	if len(data) > v.conf.MaxCredentialBytes {
+		metrics.CredentialSizeRejected.Inc(credentialTypeLabel(task.key))
+		return errors.Errorf("%s secret data of %d bytes exceeds the maximum credential size of %d bytes",
+			task.id(), len(data), v.conf.MaxCredentialBytes)
+	}
+	return nil
+}
+
+// credentialDiff describes the keys that differ between the expected and actual credential,
+// without revealing the credential values
+func credentialDiff(expected, actual map[string]string) []string {
+	diff := []string{}
+	for key, val := range expected {
+		actualVal, found := actual[key]
+		if !found {
+			diff = append(diff, fmt.Sprintf("key %s missing", key))
+		} else if actualVal != val {
+			diff = append(diff, fmt.Sprintf("key %s value differs (%d bytes written, %d bytes read)", key, len(val), len(actualVal)))
+		}
+	}
+	for key := range actual {
+		if _, found := expected[key]; !found {
+			diff = append(diff, fmt.Sprintf("unexpected key %s", key))
+		}
+	}
+	sort.Strings(diff)
+	return diff
 }
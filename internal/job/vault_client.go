@@ -0,0 +1,60 @@
+package job
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// vaultClient returns the vault client shared by the sync runs, so its token renewal outlives
+// a single run. It is created on first use and closed by Shutdown. It is created again when the
+// vault address file holds a new address, and with fallback clusters once its cluster is no
+// longer reachable.
+func (v *VaultCredSync) vaultClient(ctx context.Context) (*client.VaultClient, error) {
+	v.vaultClientMutex.Lock()
+	defer v.vaultClientMutex.Unlock()
+	address, err := client.VaultAddress(v.conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init vault client")
+	}
+	if v.vc != nil && address != v.vaultAddress {
+		v.log.Infof("vault address changed from %s to %s, creating the vault client again", v.vaultAddress, address)
+		v.vc.Close()
+		v.vc = nil
+	}
+	if v.vc != nil && len(v.conf.VaultFallbackAddresses) != 0 {
+		if err := v.vc.CheckReachable(ctx); err != nil {
+			v.log.Warn(fmt.Sprintf("selecting the vault cluster again, %s", err))
+			v.vc.Close()
+			v.vc = nil
+		}
+	}
+	if v.vc != nil {
+		return v.vc, nil
+	}
+
+	// the client is created for the address read, so it matches the address compared later on
+	conf := v.conf
+	conf.Address, conf.VaultAddressFile = address, ""
+	vc, err := client.NewVaultClientForAuthMethod(v.log, conf)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init vault client")
+	}
+	if v.credentialCache != nil {
+		vc.SetCredentialCache(v.credentialCache)
+	}
+	v.vc, v.vaultAddress = vc, address
+	return vc, nil
+}
+
+// closeVaultClient stops the token renewal of the shared vault client
+func (v *VaultCredSync) closeVaultClient() {
+	v.vaultClientMutex.Lock()
+	defer v.vaultClientMutex.Unlock()
+	if v.vc != nil {
+		v.vc.Close()
+		v.vc = nil
+	}
+}
@@ -0,0 +1,112 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+// SyncStatus reports the outcome of the latest credential sync runs
+type SyncStatus struct {
+	LastRunTime     time.Time
+	LastSuccessTime time.Time
+	LastError       error
+	// LastRunDuration and CredentialsWritten are of the latest completed run
+	LastRunDuration    time.Duration
+	CredentialsWritten int
+}
+
+func (v *VaultCredSync) recordRun(err error) {
+	v.statusMutex.Lock()
+	defer v.statusMutex.Unlock()
+	v.status.LastRunTime = time.Now()
+	v.status.LastError = err
+	if err == nil {
+		v.status.LastSuccessTime = v.status.LastRunTime
+	}
+}
+
+func (v *VaultCredSync) recordRunResult(result SyncResult) {
+	v.statusMutex.Lock()
+	defer v.statusMutex.Unlock()
+	v.status.LastRunDuration = result.Duration
+	v.status.CredentialsWritten = result.Written
+}
+
+func (v *VaultCredSync) Status() SyncStatus {
+	v.statusMutex.Lock()
+	defer v.statusMutex.Unlock()
+	return v.status
+}
+
+// HealthCheck verifies the kubernetes api server is reachable and the vault client
+// authenticates with the credential mounts in place, without performing any writes.
+// It reuses the clients of the sync, so the frequent probes do not log in to vault each time.
+func (v *VaultCredSync) HealthCheck(ctx context.Context) error {
+	k8s, err := v.k8sClient()
+	if err != nil {
+		return err
+	}
+	if err := k8s.CheckConnectivity(ctx); err != nil {
+		return err
+	}
+
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		return err
+	}
+	return vc.CheckConnectivity(ctx, v.credentialMountPaths()...)
+}
+
+// k8sClient returns the kubernetes client of the health checks, created on first use
+func (v *VaultCredSync) k8sClient() (*client.K8SClient, error) {
+	v.k8sClientMutex.Lock()
+	defer v.k8sClientMutex.Unlock()
+	if v.k8s != nil {
+		return v.k8s, nil
+	}
+	k8s, err := client.NewK8SClient(v.log)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to init k8s client")
+	}
+	v.k8s = k8s
+	return k8s, nil
+}
+
+// EnsureCredentialMounts verifies the credential mounts exist at startup, the missing
+// mounts are created as KV v2 mounts when auto create mount is configured
+func (v *VaultCredSync) EnsureCredentialMounts(ctx context.Context) error {
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		return err
+	}
+	for _, mountPath := range v.credentialMountPaths() {
+		if err := vc.EnsureMount(ctx, mountPath, v.conf.AutoCreateMount); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadinessCheck runs the health check and fails when the sync has been
+// failing for longer than the configured readiness threshold.
+func (v *VaultCredSync) ReadinessCheck(ctx context.Context) error {
+	if err := v.HealthCheck(ctx); err != nil {
+		return err
+	}
+
+	status := v.Status()
+	if status.LastError == nil {
+		return nil
+	}
+	lastSuccessTime := status.LastSuccessTime
+	if lastSuccessTime.IsZero() {
+		lastSuccessTime = v.startTime
+	}
+	if failingFor := time.Since(lastSuccessTime); failingFor > v.conf.SyncFailureThreshold {
+		return errors.WithMessagef(status.LastError, "credential sync failing for %s", failingFor.Round(time.Second))
+	}
+	return nil
+}
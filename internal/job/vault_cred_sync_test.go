@@ -0,0 +1,233 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+	"github.com/pkg/errors"
+)
+
+// newTestVaultClient returns a client of the test vault server with the given config
+func newTestVaultClient(t *testing.T, server *vaulttest.Server, conf config.VaultEnv) *client.VaultClient {
+	conf.Address = server.URL()
+	conf.VaultToken = vaulttest.Token
+	conf.TransitMountPath = vaulttest.TransitMount
+	vc, err := client.NewVaultClientForVaultToken(logging.NewLogger(), conf)
+	if err != nil {
+		t.Fatalf("failed to create vault client: %v", err)
+	}
+	return vc
+}
+
+// testSource is a credential source of fixed keys, failing with err when set
+type testSource struct {
+	data        map[string]string
+	updatedTime time.Time
+	err         error
+}
+
+func (s *testSource) Fetch(ctx context.Context) (map[string]string, time.Time, error) {
+	return s.data, s.updatedTime, s.err
+}
+
+// newTestCredSync returns a credential sync of the sources writing to the test vault server
+func newTestCredSync(t *testing.T, server *vaulttest.Server, conf config.VaultEnv, sources map[string]CredentialSource) *VaultCredSync {
+	conf.Address = server.URL()
+	conf.VaultToken = vaulttest.Token
+	v := &VaultCredSync{
+		log:             logging.NewLogger(),
+		conf:            conf,
+		credentialTypes: newCredentialTypeRegistry(nil),
+		startTime:       time.Now(),
+		shutdown:        newShutdownState(),
+	}
+	v.SetCredentialSources(sources)
+	t.Cleanup(v.closeVaultClient)
+	return v
+}
+
+// genericCredentialValue returns the sync secret value of a generic credential of the entity
+func genericCredentialValue(entityName, password string) string {
+	return `{"credentialType":"database","entityName":"` + entityName + `","credIdentifier":"db","credential":{"password":"` + password + `"}}`
+}
+
+func TestSyncKeepsCredentialsOfUnreadSources(t *testing.T) {
+	server := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	payments := &testSource{data: map[string]string{"GENERIC_DB": genericCredentialValue("payments", "p1")}, updatedTime: time.Now()}
+	orders := &testSource{data: map[string]string{"GENERIC_DB": genericCredentialValue("orders", "o1")}, updatedTime: time.Now()}
+	v := newTestCredSync(t, server, config.VaultEnv{PruneOrphans: true},
+		map[string]CredentialSource{"payments": payments, "orders": orders})
+	ctx := context.Background()
+
+	if _, err := v.runWithResult(newRunContext(ctx)); err != nil {
+		t.Fatalf("first sync error = %v", err)
+	}
+	ordersPath, found := v.syncedPaths["orders/GENERIC_DB"]
+	if !found {
+		t.Fatalf("synced paths %v have no orders credential", v.syncedPaths)
+	}
+
+	// a transient read failure keeps the credentials of the source
+	orders.err = errors.New("connection refused")
+	payments.data["GENERIC_DB"] = genericCredentialValue("payments", "p2")
+	payments.updatedTime = time.Now()
+	if _, err := v.runWithResult(newRunContext(ctx)); err == nil {
+		t.Errorf("sync error = nil with a source failing to read")
+	}
+	if _, found := server.Latest(ordersPath.mount, ordersPath.path); !found {
+		t.Errorf("credential %s of the unread source pruned", ordersPath)
+	}
+	if v.syncedPaths["orders/GENERIC_DB"] != ordersPath {
+		t.Errorf("synced paths %v lost the credential of the unread source", v.syncedPaths)
+	}
+
+	// a removed source is pruned
+	orders.err = client.ErrSecretNotFound
+	if _, err := v.runWithResult(newRunContext(ctx)); err != nil {
+		t.Fatalf("sync error = %v with a removed source", err)
+	}
+	if _, found := server.Latest(ordersPath.mount, ordersPath.path); found {
+		t.Errorf("credential %s of the removed source not pruned", ordersPath)
+	}
+}
+
+func TestSyncReportsSourceReadErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr bool
+	}{
+		{name: "unreachable source", err: errors.New("connection refused"), wantErr: true},
+		{name: "removed secret", err: client.ErrSecretNotFound},
+		{name: "removed configmap", err: client.ErrConfigMapNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"secret": 2})
+			v := newTestCredSync(t, server, config.VaultEnv{},
+				map[string]CredentialSource{"vault-cred-sync": &testSource{err: tt.err}})
+
+			_, err := v.runWithResult(newRunContext(context.Background()))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sync error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status := v.Status(); (status.LastError != nil) != tt.wantErr {
+				t.Errorf("run recorded with error %v, wantErr %v", status.LastError, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarkBinaryCredential(t *testing.T) {
+	binaryData := []byte{0x00, 0xff, 0x10, 0x80, 'k', 'e', 'y', 0x0a}
+	tests := []struct {
+		name    string
+		cred    map[string]string
+		want    map[string][]byte
+		wantErr bool
+	}{
+		{
+			name: "binary values round trip",
+			cred: map[string]string{"keystore": base64.StdEncoding.EncodeToString(binaryData), "empty": ""},
+			want: map[string][]byte{"keystore": binaryData, "empty": {}},
+		},
+		{name: "not base64", cred: map[string]string{"keystore": "not base64!"}, wantErr: true},
+		{name: "url encoding", cred: map[string]string{"keystore": base64.URLEncoding.EncodeToString([]byte{0xfb, 0xff})}, wantErr: true},
+		{name: "missing padding", cred: map[string]string{"keystore": "a2V"}, wantErr: true},
+		{name: "one invalid value", cred: map[string]string{"valid": "a2V5", "invalid": "%%%"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := markBinaryCredential(tt.cred)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("markBinaryCredential() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.cred[credentialEncodingKey] != certEncodingBase64 {
+				t.Errorf("encoding = %q, want %q", tt.cred[credentialEncodingKey], certEncodingBase64)
+			}
+			for key, want := range tt.want {
+				got, err := base64.StdEncoding.DecodeString(tt.cred[key])
+				if err != nil || !bytes.Equal(got, want) {
+					t.Errorf("stored %s decodes to %v, %v, want %v", key, got, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVaultClientFollowsAddressFile(t *testing.T) {
+	first := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	second := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	addressFile := filepath.Join(t.TempDir(), "vault-addr")
+	if err := os.WriteFile(addressFile, []byte(first.URL()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := newTestCredSync(t, first, config.VaultEnv{VaultAddressFile: addressFile}, nil)
+	ctx := context.Background()
+
+	vc, err := v.vaultClient(ctx)
+	if err != nil {
+		t.Fatalf("vaultClient() error = %v", err)
+	}
+	if again, _ := v.vaultClient(ctx); again != vc {
+		t.Errorf("vaultClient() created again with the same address")
+	}
+
+	if err := os.WriteFile(addressFile, []byte(second.URL()+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := v.vaultClient(ctx)
+	if err != nil {
+		t.Fatalf("vaultClient() error = %v", err)
+	}
+	if rotated == vc || rotated.Address() != second.URL() {
+		t.Errorf("vaultClient() address = %s after the address file rotated to %s", rotated.Address(), second.URL())
+	}
+}
+
+func TestSyncMergedCredentialsSkipsOnlyFailedEntities(t *testing.T) {
+	server := vaulttest.NewServer(t, map[string]int{"secret": 2})
+	credential := func(entityName, key, value string) string {
+		return `{"credentialType":"database","entityName":"` + entityName + `","credIdentifier":"db","credential":{"` + key + `":"` + value + `"}}`
+	}
+	source := &testSource{data: map[string]string{
+		"GENERIC_PAYMENTS_USER":     credential("payments", "user", "u1"),
+		"GENERIC_PAYMENTS_PASSWORD": credential("payments", "password", "p1"),
+		"GENERIC_ORDERS_PASSWORD":   credential("orders", "password", "o1"),
+	}, updatedTime: time.Now()}
+	v := newTestCredSync(t, server, config.VaultEnv{MergeByEntity: true}, map[string]CredentialSource{"vault-cred-sync": source})
+	ctx := context.Background()
+
+	if _, err := v.runWithResult(newRunContext(ctx)); err != nil {
+		t.Fatalf("first sync error = %v", err)
+	}
+	if data, _ := server.Latest("secret", "entity/payments/credentials"); data["user"] != "u1" || data["password"] != "p1" {
+		t.Fatalf("merged payments credential = %v", data)
+	}
+
+	source.data["GENERIC_PAYMENTS_PASSWORD"] = "not json"
+	source.data["GENERIC_PAYMENTS_USER"] = credential("payments", "user", "u2")
+	source.data["GENERIC_ORDERS_PASSWORD"] = credential("orders", "password", "o2")
+	source.updatedTime = time.Now()
+	if _, err := v.runWithResult(newRunContext(ctx)); err == nil {
+		t.Errorf("sync error = nil with a failed key")
+	}
+	if data, _ := server.Latest("secret", "entity/orders/credentials"); data["password"] != "o2" {
+		t.Errorf("merged credential of the unrelated entity = %v, want it written", data)
+	}
+	if data, _ := server.Latest("secret", "entity/payments/credentials"); data["user"] != "u1" || data["password"] != "p1" {
+		t.Errorf("merged credential of the failed key = %v, want it kept unchanged", data)
+	}
+}
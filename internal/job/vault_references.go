@@ -0,0 +1,101 @@
+package job
+
+import (
+	"context"
+	"strings"
+
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/pkg/errors"
+)
+
+const (
+	// vaultReferencePrefix marks a credential value referencing the key of another vault secret,
+	// vault://<mount>/<path>#<key>
+	vaultReferencePrefix = "vault://"
+	// maxReferenceDepth limits the references followed from a referenced value
+	maxReferenceDepth = 5
+)
+
+// resolveCredentialReferences replaces the credential values referencing another vault secret
+// with the referenced value, read at write time. Only the secrets under the allowed reference
+// paths can be referenced, as they are read with the token of the job.
+func (v *VaultCredSync) resolveCredentialReferences(ctx context.Context, vc *client.VaultClient, cred map[string]string) error {
+	if !v.conf.ResolveReferences {
+		return nil
+	}
+	for key, val := range cred {
+		if !strings.HasPrefix(val, vaultReferencePrefix) {
+			continue
+		}
+		resolved, err := v.resolveReference(ctx, vc, val, map[string]bool{})
+		if err != nil {
+			return errors.WithMessagef(err, "failed to resolve the reference of credential %s", key)
+		}
+		cred[key] = resolved
+	}
+	return nil
+}
+
+// resolveReference reads the referenced value, following the references of referenced values
+// up to the max depth, a reference visited twice is a cycle
+func (v *VaultCredSync) resolveReference(ctx context.Context, vc *client.VaultClient, reference string, visited map[string]bool) (string, error) {
+	if visited[reference] {
+		return "", errors.Errorf("reference cycle at %s", reference)
+	}
+	if len(visited) >= maxReferenceDepth {
+		return "", errors.Errorf("reference %s exceeds the max depth of %d references", reference, maxReferenceDepth)
+	}
+	visited[reference] = true
+
+	mountPath, secretPath, key, err := parseVaultReference(reference)
+	if err != nil {
+		return "", err
+	}
+	if !v.referenceAllowed(mountPath, secretPath) {
+		return "", errors.Errorf("reference %s is not under the allowed reference paths", reference)
+	}
+	var cred map[string]string
+	err = v.vaultOp(ctx, "read", func(ctx context.Context) (err error) {
+		cred, err = vc.GetCredential(ctx, mountPath, secretPath)
+		return
+	})
+	if err != nil {
+		return "", errors.WithMessagef(err, "failed to read referenced secret %s", reference)
+	}
+	val, found := cred[key]
+	if !found {
+		return "", errors.Errorf("referenced key not found for %s", reference)
+	}
+	if strings.HasPrefix(val, vaultReferencePrefix) {
+		return v.resolveReference(ctx, vc, val, visited)
+	}
+	return val, nil
+}
+
+// referenceAllowed reports whether the secret is one of the allowed reference paths or under one,
+// the allowed paths are <mount> or <mount>/<path prefix> matched by whole path segments
+func (v *VaultCredSync) referenceAllowed(mountPath, secretPath string) bool {
+	for _, segment := range strings.Split(secretPath, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	target := mountPath + "/" + secretPath
+	for _, allowedPath := range v.conf.ReferenceAllowedPaths {
+		allowedPath = strings.Trim(allowedPath, "/")
+		if allowedPath != "" && (target == allowedPath || strings.HasPrefix(target, allowedPath+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVaultReference splits a vault://<mount>/<path>#<key> reference
+func parseVaultReference(reference string) (mountPath, secretPath, key string, err error) {
+	target, key, found := strings.Cut(strings.TrimPrefix(reference, vaultReferencePrefix), "#")
+	mountPath, secretPath, _ = strings.Cut(strings.Trim(target, "/"), "/")
+	if !found || key == "" || mountPath == "" || secretPath == "" {
+		return "", "", "", errors.Errorf("invalid reference %s, expected vault://<mount>/<path>#<key>", reference)
+	}
+	return mountPath, secretPath, key, nil
+}
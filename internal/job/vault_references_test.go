@@ -0,0 +1,51 @@
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/intelops/vault-cred/config"
+	"github.com/intelops/vault-cred/internal/vaulttest"
+)
+
+func TestResolveCredentialReferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		allowedPaths []string
+		reference    string
+		want         string
+		wantErr      bool
+	}{
+		{name: "allowed mount", allowedPaths: []string{"shared"}, reference: "vault://shared/db/payments#password", want: "s3cret"},
+		{name: "allowed path prefix", allowedPaths: []string{"/shared/db/"}, reference: "vault://shared/db/payments#password", want: "s3cret"},
+		{name: "allowed path", allowedPaths: []string{"shared/db/payments"}, reference: "vault://shared/db/payments#password", want: "s3cret"},
+		{name: "no allowed paths", reference: "vault://shared/db/payments#password", wantErr: true},
+		{name: "other mount", allowedPaths: []string{"shared"}, reference: "vault://secret/admin#token", wantErr: true},
+		{name: "partial segment", allowedPaths: []string{"shared/d"}, reference: "vault://shared/db/payments#password", wantErr: true},
+		{name: "dot dot segment", allowedPaths: []string{"shared/db"}, reference: "vault://shared/db/../../secret/admin#token", wantErr: true},
+		{name: "followed reference outside", allowedPaths: []string{"shared/db"}, reference: "vault://shared/db/linked#password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := vaulttest.NewServer(t, map[string]int{"shared": 2, "secret": 2})
+			server.Seed("shared", "db/payments", map[string]interface{}{"password": "s3cret"})
+			server.Seed("shared", "db/linked", map[string]interface{}{"password": "vault://secret/admin#token"})
+			server.Seed("secret", "admin", map[string]interface{}{"token": "root"})
+			conf := config.VaultEnv{ResolveReferences: true, ReferenceAllowedPaths: tt.allowedPaths}
+			vc := newTestVaultClient(t, server, conf)
+			v := &VaultCredSync{conf: conf}
+
+			cred := map[string]string{"password": tt.reference}
+			err := v.resolveCredentialReferences(context.Background(), vc, cred)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveCredentialReferences() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cred["password"] != tt.want {
+				t.Errorf("resolved to %q, want %q", cred["password"], tt.want)
+			}
+			if tt.wantErr && server.RequestCount("GET", "secret/data/admin") != 0 {
+				t.Errorf("secret outside the allowed paths read")
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	counterType = "counter"
+	gaugeType   = "gauge"
+)
+
+var (
+	SyncRuns          = newCounter("vaultcred_sync_runs_total", "Total number of vault credential sync runs.")
+	SyncErrors        = newCounterVec("vaultcred_sync_errors_total", "Total number of credentials failed to sync.", "type")
+	LastSyncTimestamp = newGauge("vaultcred_last_sync_timestamp_seconds", "Unix time of the last successful vault credential sync.")
+	CertExpiring      = newCounter("vaultcred_cert_expiring_total", "Total number of synced certificates found close to expiry.")
+	SyncSkipped       = newCounter("vaultcred_sync_skipped_total", "Total number of credential writes skipped as unchanged.")
+	VaultSealed       = newGauge("vaultcred_vault_sealed", "Whether vault was found sealed by the last credential sync, 1 when sealed.")
+
+	CredentialSizeRejected = newCounterVec("vaultcred_credential_size_rejected_total",
+		"Total number of credentials rejected for exceeding the maximum credential size.", "type")
+)
+
+var registry = &metricRegistry{}
+
+type metricRegistry struct {
+	mutex   sync.Mutex
+	metrics []*metric
+}
+
+func (r *metricRegistry) register(m *metric) *metric {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+// metric holds the values of a metric by label value, the empty label value
+// is used for metrics without a label.
+type metric struct {
+	name      string
+	help      string
+	kind      string
+	labelName string
+	mutex     sync.Mutex
+	values    map[string]float64
+}
+
+func (m *metric) add(labelValue string, val float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values[labelValue] += val
+}
+
+func (m *metric) set(labelValue string, val float64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.values[labelValue] = val
+}
+
+func (m *metric) write(w io.Writer) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+	if m.labelName == "" {
+		fmt.Fprintf(w, "%s %v\n", m.name, m.values[""])
+		return
+	}
+
+	labelValues := make([]string, 0, len(m.values))
+	for labelValue := range m.values {
+		labelValues = append(labelValues, labelValue)
+	}
+	sort.Strings(labelValues)
+	for _, labelValue := range labelValues {
+		fmt.Fprintf(w, "%s{%s=%q} %v\n", m.name, m.labelName, labelValue, m.values[labelValue])
+	}
+}
+
+type Counter struct {
+	m *metric
+}
+
+func newCounter(name, help string) *Counter {
+	return &Counter{m: registry.register(&metric{name: name, help: help, kind: counterType,
+		values: map[string]float64{}})}
+}
+
+func (c *Counter) Inc() {
+	c.m.add("", 1)
+}
+
+type CounterVec struct {
+	m *metric
+}
+
+func newCounterVec(name, help, labelName string) *CounterVec {
+	return &CounterVec{m: registry.register(&metric{name: name, help: help, kind: counterType,
+		labelName: labelName, values: map[string]float64{}})}
+}
+
+func (c *CounterVec) Inc(labelValue string) {
+	c.m.add(labelValue, 1)
+}
+
+type Gauge struct {
+	m *metric
+}
+
+func newGauge(name, help string) *Gauge {
+	return &Gauge{m: registry.register(&metric{name: name, help: help, kind: gaugeType,
+		values: map[string]float64{}})}
+}
+
+func (g *Gauge) Set(val float64) {
+	g.m.set("", val)
+}
+
+func (g *Gauge) SetToCurrentTime() {
+	g.Set(float64(time.Now().Unix()))
+}
+
+// Handler serves all registered metrics in the prometheus text exposition format.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		registry.mutex.Lock()
+		defer registry.mutex.Unlock()
+		for _, m := range registry.metrics {
+			m.write(w)
+		}
+	})
+}
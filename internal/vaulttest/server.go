@@ -0,0 +1,397 @@
+// Package vaulttest provides an in memory vault server for the tests of the vault clients
+package vaulttest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is an in memory vault server with KV v1 and v2 mounts and a transit engine,
+// implementing the endpoints used by the vault client
+type Server struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+	// mounts are the KV versions of the mounts
+	mounts   map[string]int
+	secrets  map[string]*kvSecret
+	requests []string
+	// ciphertexts are the plaintexts of the transit ciphertexts
+	ciphertexts map[string]string
+	sealed      bool
+}
+
+type kvSecret struct {
+	// versions are the data of each version, nil when the version is deleted
+	versions       []map[string]interface{}
+	customMetadata map[string]interface{}
+}
+
+const (
+	// TransitMount is the mount path of the transit engine
+	TransitMount = "transit"
+	// Token is the token accepted by the server
+	Token = "test-token"
+
+	kvVersion1              = 1
+	transitCiphertextPrefix = "vault:v"
+)
+
+// NewServer starts the vault server with the KV mounts and their versions, it is closed with the test
+func NewServer(t *testing.T, mounts map[string]int) *Server {
+	s := &Server{
+		mounts:      mounts,
+		secrets:     map[string]*kvSecret{},
+		ciphertexts: map[string]string{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.server.Close)
+	return s
+}
+
+// URL returns the address of the server
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Seed writes the data as a new version of the secret
+func (s *Server) Seed(mountPath, secretPath string, data map[string]interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.putLocked(mountPath+"/"+secretPath, data)
+}
+
+// Latest returns the data of the latest version of the secret
+func (s *Server) Latest(mountPath, secretPath string) (map[string]interface{}, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	secret, found := s.secrets[mountPath+"/"+secretPath]
+	if !found || len(secret.versions) == 0 {
+		return nil, false
+	}
+	data := secret.versions[len(secret.versions)-1]
+	return data, data != nil
+}
+
+// RequestCount returns the number of requests with the method, LIST for list requests, and the path without /v1/
+func (s *Server) RequestCount(method, path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	count := 0
+	for _, request := range s.requests {
+		if request == method+" "+path {
+			count++
+		}
+	}
+	return count
+}
+
+// SetSealed makes the server answer every request as a sealed vault does
+func (s *Server) SetSealed(sealed bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.sealed = sealed
+}
+
+func (s *Server) putLocked(key string, data map[string]interface{}) int {
+	secret, found := s.secrets[key]
+	if !found {
+		secret = &kvSecret{customMetadata: map[string]interface{}{}}
+		s.secrets[key] = secret
+	}
+	secret.versions = append(secret.versions, data)
+	return len(secret.versions)
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	method := r.Method
+	if method == http.MethodGet && r.URL.Query().Get("list") == "true" {
+		method = "LIST"
+	}
+	s.requests = append(s.requests, method+" "+path)
+
+	body := map[string]interface{}{}
+	if r.ContentLength != 0 {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+	}
+
+	switch path {
+	case "sys/seal-status":
+		writeFakeJSON(w, http.StatusOK, map[string]interface{}{"type": "shamir", "initialized": true, "sealed": s.sealed})
+		return
+	case "sys/health":
+		status := http.StatusOK
+		if s.sealed {
+			status = http.StatusServiceUnavailable
+		}
+		writeFakeJSON(w, status, map[string]interface{}{"initialized": true, "sealed": s.sealed, "standby": false})
+		return
+	}
+	if s.sealed {
+		writeFakeErrors(w, http.StatusServiceUnavailable, "Vault is sealed")
+		return
+	}
+	if path == "auth/token/lookup-self" {
+		// the token does not expire, so the clients do not renew it
+		writeFakeResponse(w, http.StatusOK, map[string]interface{}{"ttl": 0, "renewable": false})
+		return
+	}
+	if path == "sys/mounts" && method == http.MethodGet {
+		mounts := map[string]interface{}{}
+		for mountPath := range s.mounts {
+			mounts[mountPath+"/"] = map[string]interface{}{"type": "kv"}
+		}
+		writeFakeResponse(w, http.StatusOK, mounts)
+		return
+	}
+	if mountPath := strings.TrimPrefix(path, "sys/mounts/"); mountPath != path && method == http.MethodPost {
+		s.mounts[mountPath] = 2
+		writeFakeResponse(w, http.StatusNoContent, nil)
+		return
+	}
+	if mountPath := strings.TrimPrefix(path, "sys/internal/ui/mounts/"); mountPath != path {
+		version, found := s.mounts[mountPath]
+		if !found {
+			writeFakeResponse(w, http.StatusNotFound, nil)
+			return
+		}
+		options := map[string]interface{}{"version": "2"}
+		if version == kvVersion1 {
+			options = nil
+		}
+		writeFakeResponse(w, http.StatusOK, map[string]interface{}{"type": "kv", "options": options})
+		return
+	}
+
+	parts := strings.SplitN(path, "/", 3)
+	if parts[0] == TransitMount && len(parts) == 3 {
+		s.serveTransit(w, parts[1], body)
+		return
+	}
+	version, found := s.mounts[parts[0]]
+	if !found {
+		writeFakeResponse(w, http.StatusNotFound, nil)
+		return
+	}
+	if version == kvVersion1 {
+		s.serveKVv1(w, method, parts[0], strings.Join(parts[1:], "/"), body)
+		return
+	}
+	if len(parts) == 2 && parts[1] == "metadata" && method == "LIST" {
+		s.serveList(w, parts[0], "")
+		return
+	}
+	if len(parts) < 3 {
+		writeFakeResponse(w, http.StatusNotFound, nil)
+		return
+	}
+	switch parts[1] {
+	case "data":
+		s.serveKVv2Data(w, method, parts[0]+"/"+parts[2], body)
+	case "metadata":
+		s.serveKVv2Metadata(w, method, parts[0], parts[2], body)
+	default:
+		writeFakeResponse(w, http.StatusNotFound, nil)
+	}
+}
+
+func (s *Server) serveKVv1(w http.ResponseWriter, method, mountPath, secretPath string, body map[string]interface{}) {
+	key := mountPath + "/" + secretPath
+	switch method {
+	case "LIST":
+		s.serveList(w, mountPath, secretPath)
+	case http.MethodGet:
+		secret, found := s.secrets[key]
+		if !found {
+			writeFakeResponse(w, http.StatusNotFound, nil)
+			return
+		}
+		writeFakeResponse(w, http.StatusOK, secret.versions[len(secret.versions)-1])
+	case http.MethodPut, http.MethodPost:
+		s.secrets[key] = &kvSecret{versions: []map[string]interface{}{body}}
+		writeFakeResponse(w, http.StatusNoContent, nil)
+	case http.MethodDelete:
+		delete(s.secrets, key)
+		writeFakeResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func (s *Server) serveKVv2Data(w http.ResponseWriter, method, key string, body map[string]interface{}) {
+	secret, found := s.secrets[key]
+	switch method {
+	case http.MethodGet:
+		if !found || len(secret.versions) == 0 {
+			writeFakeResponse(w, http.StatusNotFound, nil)
+			return
+		}
+		version := len(secret.versions)
+		data := secret.versions[version-1]
+		metadata := fakeVersionMetadata(version, data == nil)
+		metadata["custom_metadata"] = secret.customMetadata
+		status := http.StatusOK
+		if data == nil {
+			status = http.StatusNotFound
+		}
+		writeFakeResponse(w, status, map[string]interface{}{"data": data, "metadata": metadata})
+	case http.MethodPut, http.MethodPost:
+		current := 0
+		if found {
+			current = len(secret.versions)
+		}
+		if options, ok := body["options"].(map[string]interface{}); ok {
+			if cas, ok := options["cas"].(float64); ok && int(cas) != current {
+				writeFakeErrors(w, http.StatusBadRequest, "check-and-set parameter did not match the current version")
+				return
+			}
+		}
+		data, _ := body["data"].(map[string]interface{})
+		writeFakeResponse(w, http.StatusOK, fakeVersionMetadata(s.putLocked(key, data), false))
+	case http.MethodDelete:
+		if found && len(secret.versions) != 0 {
+			secret.versions[len(secret.versions)-1] = nil
+		}
+		writeFakeResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+func (s *Server) serveKVv2Metadata(w http.ResponseWriter, method, mountPath, secretPath string, body map[string]interface{}) {
+	key := mountPath + "/" + secretPath
+	secret, found := s.secrets[key]
+	switch method {
+	case "LIST":
+		s.serveList(w, mountPath, secretPath)
+	case http.MethodGet:
+		if !found {
+			writeFakeResponse(w, http.StatusNotFound, nil)
+			return
+		}
+		versions := map[string]interface{}{}
+		for i, data := range secret.versions {
+			versions[fmt.Sprintf("%d", i+1)] = fakeVersionMetadata(i+1, data == nil)
+		}
+		writeFakeResponse(w, http.StatusOK, map[string]interface{}{
+			"current_version": len(secret.versions),
+			"custom_metadata": secret.customMetadata,
+			"versions":        versions,
+		})
+	case http.MethodPatch, http.MethodPut, http.MethodPost:
+		if !found {
+			secret = &kvSecret{customMetadata: map[string]interface{}{}}
+			s.secrets[key] = secret
+		}
+		customMetadata, _ := body["custom_metadata"].(map[string]interface{})
+		if method != http.MethodPatch {
+			secret.customMetadata = map[string]interface{}{}
+		}
+		for name, val := range customMetadata {
+			if val == nil {
+				delete(secret.customMetadata, name)
+				continue
+			}
+			secret.customMetadata[name] = val
+		}
+		writeFakeResponse(w, http.StatusNoContent, nil)
+	case http.MethodDelete:
+		delete(s.secrets, key)
+		writeFakeResponse(w, http.StatusNoContent, nil)
+	}
+}
+
+// serveList lists the secret names and the sub directories under the directory of the mount
+func (s *Server) serveList(w http.ResponseWriter, mountPath, dir string) {
+	prefix := mountPath + "/"
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		prefix += dir + "/"
+	}
+	keySet := map[string]bool{}
+	for key := range s.secrets {
+		name := strings.TrimPrefix(key, prefix)
+		if name == key {
+			continue
+		}
+		if i := strings.Index(name, "/"); i != -1 {
+			name = name[:i+1]
+		}
+		keySet[name] = true
+	}
+	if len(keySet) == 0 {
+		writeFakeResponse(w, http.StatusNotFound, nil)
+		return
+	}
+	keys := []string{}
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	writeFakeResponse(w, http.StatusOK, map[string]interface{}{"keys": keys})
+}
+
+// serveTransit encrypts to a new ciphertext on every call, as the transit engine does
+func (s *Server) serveTransit(w http.ResponseWriter, operation string, body map[string]interface{}) {
+	switch operation {
+	case "encrypt":
+		ciphertext := fmt.Sprintf("%s1:%d", transitCiphertextPrefix, len(s.ciphertexts)+1)
+		s.ciphertexts[ciphertext], _ = body["plaintext"].(string)
+		writeFakeResponse(w, http.StatusOK, map[string]interface{}{"ciphertext": ciphertext})
+	case "decrypt":
+		ciphertext, _ := body["ciphertext"].(string)
+		plaintext, found := s.ciphertexts[ciphertext]
+		if !found {
+			writeFakeErrors(w, http.StatusBadRequest, "invalid ciphertext")
+			return
+		}
+		writeFakeResponse(w, http.StatusOK, map[string]interface{}{"plaintext": plaintext})
+	default:
+		writeFakeResponse(w, http.StatusNotFound, nil)
+	}
+}
+
+func fakeVersionMetadata(version int, deleted bool) map[string]interface{} {
+	deletionTime := ""
+	if deleted {
+		deletionTime = time.Now().UTC().Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"version":       version,
+		"created_time":  time.Now().UTC().Format(time.RFC3339),
+		"deletion_time": deletionTime,
+		"destroyed":     false,
+	}
+}
+
+func writeFakeResponse(w http.ResponseWriter, status int, data map[string]interface{}) {
+	if data == nil {
+		if status == http.StatusNoContent {
+			w.WriteHeader(status)
+			return
+		}
+		writeFakeErrors(w, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeFakeErrors(w http.ResponseWriter, status int, errs ...string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"errors": append([]string{}, errs...)})
+}
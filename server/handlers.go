@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/intelops/go-common/logging"
+	"github.com/intelops/vault-cred/internal/job"
+)
+
+const (
+	healthCheckTimeout = 10 * time.Second
+	bearerPrefix       = "Bearer "
+)
+
+type healthResponse struct {
+	Status          string `json:"status"`
+	Error           string `json:"error,omitempty"`
+	LastSyncSuccess *bool  `json:"lastSyncSuccess,omitempty"`
+	LastSuccessTime string `json:"lastSuccessTime,omitempty"`
+	LastSuccessAge  string `json:"lastSuccessAge,omitempty"`
+}
+
+// healthHandler serves the cred sync health checks. Liveness is local to the process, so a vault
+// or kubernetes outage marks the pods not ready instead of restarting them. Readiness checks both
+// and considers how long the credential sync has been failing.
+func healthHandler(credSync *job.VaultCredSync, readiness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if credSync == nil || !readiness {
+			writeJSON(w, http.StatusOK, healthResponse{Status: "ok"})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), healthCheckTimeout)
+		defer cancel()
+
+		resp := healthResponse{Status: "ok"}
+		err := credSync.ReadinessCheck(ctx)
+		status := credSync.Status()
+		if !status.LastRunTime.IsZero() {
+			lastSyncSuccess := status.LastError == nil
+			resp.LastSyncSuccess = &lastSyncSuccess
+		}
+		if !status.LastSuccessTime.IsZero() {
+			resp.LastSuccessTime = status.LastSuccessTime.Format(time.RFC3339)
+			resp.LastSuccessAge = time.Since(status.LastSuccessTime).Round(time.Second).String()
+		}
+
+		if err != nil {
+			resp.Status = "failed"
+			resp.Error = err.Error()
+			writeJSON(w, http.StatusServiceUnavailable, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+type statusResponse struct {
+	LastRunTime        string `json:"last_run_time,omitempty"`
+	LastRunDuration    string `json:"last_run_duration,omitempty"`
+	LastError          string `json:"last_error,omitempty"`
+	CredentialsWritten int    `json:"credentials_written"`
+	NextScheduledRun   string `json:"next_scheduled_run,omitempty"`
+}
+
+// statusHandler serves the status of the most recent completed credential sync run, read only
+func statusHandler(credSync *job.VaultCredSync, nextRun func() (time.Time, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		status := credSync.Status()
+		resp := statusResponse{CredentialsWritten: status.CredentialsWritten}
+		if !status.LastRunTime.IsZero() {
+			resp.LastRunTime = status.LastRunTime.Format(time.RFC3339)
+			resp.LastRunDuration = status.LastRunDuration.Round(time.Millisecond).String()
+		}
+		if status.LastError != nil {
+			resp.LastError = status.LastError.Error()
+		}
+		if next, scheduled := nextRun(); scheduled {
+			resp.NextScheduledRun = next.Format(time.RFC3339)
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+type syncResponse struct {
+	job.SyncResult
+	Error string `json:"error,omitempty"`
+}
+
+// syncHandler triggers a credential sync on demand and responds with the sync summary
+func syncHandler(log logging.Logger, credSync *job.VaultCredSync) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// the run is detached from the request so a client disconnect does not abort it
+		// halfway through the writes, it is bounded by the sync run timeout instead
+		ctx := context.Background()
+		if timeout := credSync.SyncRunTimeout(); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		if forceParam := r.URL.Query().Get("force"); forceParam != "" {
+			force, err := strconv.ParseBool(forceParam)
+			if err != nil {
+				http.Error(w, "invalid force parameter", http.StatusBadRequest)
+				return
+			}
+			if force {
+				ctx = job.WithForceResync(ctx)
+			}
+		}
+		result, err := credSync.SyncNow(ctx)
+		resp := syncResponse{SyncResult: result}
+		if err != nil {
+			log.Errorf("on demand vault credential sync failed, %s", err)
+			resp.Error = err.Error()
+			writeJSON(w, http.StatusInternalServerError, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// resetHandler clears the credential sync state, so the next run is a full sync
+func resetHandler(credSync *job.VaultCredSync) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		credSync.Reset()
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reset"})
+	})
+}
+
+type diffResponse struct {
+	job.DiffReport
+	Error string `json:"error,omitempty"`
+}
+
+// diffHandler reports the drift of the credentials in vault from the sync sources, without any writes
+func diffHandler(log logging.Logger, credSync *job.VaultCredSync) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report, err := credSync.Diff(r.Context())
+		resp := diffResponse{DiffReport: report}
+		if err != nil {
+			log.Errorf("vault credential diff failed, %s", err)
+			resp.Error = err.Error()
+			writeJSON(w, http.StatusInternalServerError, resp)
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	})
+}
+
+// requireBearerToken rejects the requests without the bearer token in the authorization header
+func requireBearerToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, bearerPrefix) ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(authHeader, bearerPrefix)), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, resp interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(resp)
+}
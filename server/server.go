@@ -1,23 +1,34 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/intelops/vault-cred/internal/job"
 
 	"github.com/intelops/go-common/logging"
 	"github.com/intelops/vault-cred/config"
 	"github.com/intelops/vault-cred/internal/api"
+	"github.com/intelops/vault-cred/internal/client"
+	"github.com/intelops/vault-cred/internal/metrics"
 	"github.com/intelops/vault-cred/proto/pb/vaultcredpb"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 
 	"google.golang.org/grpc/reflection"
 )
 
+const (
+	credSyncJobName         = "vault-cred-sync"
+	mountCheckRetryInterval = 15 * time.Second
+)
+
 func Start() {
 	log := logging.NewLogger()
 
@@ -50,19 +61,59 @@ func Start() {
 		}
 	}()
 
-	s := initScheduler(log, cfg)
+	s, credSync := initScheduler(log, cfg)
 	s.Start()
+	if credSync != nil {
+		go ensureCredentialMounts(log, credSync)
+	}
+
+	httpServer := startHTTPServer(log, cfg, s, credSync)
 
 	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 	<-signals
 
 	s.Stop()
+	if credSync != nil {
+		credSync.Shutdown(cfg.ShutdownGracePeriod)
+	}
+	httpServer.Close()
 	grpcServer.Stop()
 	log.Debug("exiting vault-cred server")
 }
 
-func initScheduler(log logging.Logger, cfg config.Configuration) (s *job.Scheduler) {
+func startHTTPServer(log logging.Logger, cfg config.Configuration, s *job.Scheduler, credSync *job.VaultCredSync) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.Handle("/healthz", healthHandler(credSync, false))
+	mux.Handle("/readyz", healthHandler(credSync, true))
+	if credSync != nil && cfg.SyncAPIToken != "" {
+		mux.Handle("/sync", requireBearerToken(cfg.SyncAPIToken, syncHandler(log, credSync)))
+		mux.Handle("/diff", requireBearerToken(cfg.SyncAPIToken, diffHandler(log, credSync)))
+		mux.Handle("/reset", requireBearerToken(cfg.SyncAPIToken, resetHandler(credSync)))
+	}
+	if credSync != nil {
+		var handler http.Handler = statusHandler(credSync, func() (time.Time, bool) {
+			return s.NextRun(credSyncJobName)
+		})
+		if cfg.StatusRequiresToken && cfg.SyncAPIToken != "" {
+			handler = requireBearerToken(cfg.SyncAPIToken, handler)
+		}
+		mux.Handle("/status", handler)
+	}
+
+	addr := fmt.Sprintf("%s:%d", cfg.Host, cfg.HTTPPort)
+	httpServer := &http.Server{Addr: addr, Handler: mux}
+	log.Infof("HTTP server listening at %s", addr)
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("failed to start http server, %v", err)
+		}
+	}()
+	return httpServer
+}
+
+func initScheduler(log logging.Logger, cfg config.Configuration) (s *job.Scheduler, credSync *job.VaultCredSync) {
 	s = job.NewScheduler(log)
 	if cfg.VaultSealWatchInterval != "" {
 		sj, err := job.NewVaultSealWatcher(log, cfg.VaultSealWatchInterval)
@@ -88,15 +139,37 @@ func initScheduler(log logging.Logger, cfg config.Configuration) (s *job.Schedul
 	}
 
 	if cfg.VaultCredSyncInterval != "" {
-		pj, err := job.NewVaultCredSync(log, cfg.VaultCredSyncInterval)
+		var err error
+		credSync, err = job.NewVaultCredSync(log, cfg.VaultCredSyncInterval)
 		if err != nil {
 			log.Fatal("failed to init cred sync job", err)
 		}
 
-		err = s.AddJob("vault-cred-sync", pj)
+		err = s.AddJob(credSyncJobName, credSync)
 		if err != nil {
 			log.Fatal("failed to add cred sync job", err)
 		}
+
+		if err := credSync.StartWatch(); err != nil {
+			log.Fatal("failed to start cred sync watch", err)
+		}
 	}
 	return
 }
+
+// ensureCredentialMounts verifies the credential mounts once the scheduler runs, so the seal watcher
+// can unseal a fresh vault meanwhile. The check is retried while vault is sealed or unreachable,
+// only a mount confirmed missing stops the server.
+func ensureCredentialMounts(log logging.Logger, credSync *job.VaultCredSync) {
+	for {
+		err := credSync.EnsureCredentialMounts(context.Background())
+		if err == nil {
+			return
+		}
+		if errors.Is(err, client.ErrMountNotFound) {
+			log.Fatal("credential mount check failed", err)
+		}
+		log.Warn(fmt.Sprintf("credential mount check failed, retrying in %s, %v", mountCheckRetryInterval, err))
+		time.Sleep(mountCheckRetryInterval)
+	}
+}